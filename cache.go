@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"go.etcd.io/bbolt"
+	bberrors "go.etcd.io/bbolt/errors"
+)
+
+const (
+	cacheUsage = "usage: lazytraq cache stats|clean"
+	// cacheDBLockTimeout is how long clean waits for a cache database locked by a
+	// running lazytraq.
+	cacheDBLockTimeout = 100 * time.Millisecond
+)
+
+// runCacheCommand implements `lazytraq cache stats|clean`.
+func runCacheCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New(cacheUsage)
+	}
+
+	stampImageStore, err := traqapiext.NewStampImageStore(traqapiext.DefaultStampImageCacheSize)
+	if err != nil {
+		return fmt.Errorf("create stamp image store: %w", err)
+	}
+
+	cacheDir, err := traqapiext.CacheDir()
+	if err != nil {
+		return fmt.Errorf("get cache dir: %w", err)
+	}
+
+	dbPaths, err := filepath.Glob(filepath.Join(cacheDir, "*.db"))
+	if err != nil {
+		return fmt.Errorf("find cache databases: %w", err)
+	}
+
+	switch args[0] {
+	case "stats":
+		stats, err := stampImageStore.Stats()
+		if err != nil {
+			return fmt.Errorf("get stamp image cache stats: %w", err)
+		}
+
+		fmt.Printf("stamp images: %d files, %s / %s (%s)\n",
+			stats.Count,
+			formatBytes(stats.Size),
+			formatBytes(stats.MaxSize),
+			stats.Dir,
+		)
+
+		for _, dbPath := range dbPaths {
+			info, err := os.Stat(dbPath)
+			if err != nil {
+				return fmt.Errorf("stat cache database: %w", err)
+			}
+
+			// each host has a database of its users, channels, stamps and messages
+			host := strings.TrimSuffix(filepath.Base(dbPath), ".db")
+			fmt.Printf("cache db:     %s, %s (%s)\n", host, formatBytes(info.Size()), dbPath)
+		}
+
+	case "clean":
+		if err := stampImageStore.Clean(); err != nil {
+			return fmt.Errorf("clean stamp image cache: %w", err)
+		}
+
+		for _, dbPath := range dbPaths {
+			removed, err := removeCacheDB(dbPath)
+			if err != nil {
+				return err
+			}
+
+			if !removed {
+				fmt.Printf("skipped %s, which is in use by a running lazytraq\n", dbPath)
+			}
+		}
+
+		fmt.Printf("removed caches in %s\n", cacheDir)
+
+	default:
+		return errors.New(cacheUsage)
+	}
+
+	return nil
+}
+
+// removeCacheDB removes the cache database unless another lazytraq holds its lock.
+func removeCacheDB(dbPath string) (removed bool, err error) {
+	db, err := bbolt.Open(dbPath, 0o600, &bbolt.Options{Timeout: cacheDBLockTimeout})
+	if err != nil {
+		if errors.Is(err, bberrors.ErrTimeout) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("open cache database (%s): %w", dbPath, err)
+	}
+
+	if err := db.Close(); err != nil {
+		return false, fmt.Errorf("close cache database (%s): %w", dbPath, err)
+	}
+
+	if err := os.Remove(dbPath); err != nil {
+		return false, fmt.Errorf("remove cache database: %w", err)
+	}
+
+	return true, nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -3,6 +3,7 @@ module github.com/ras0q/lazytraq
 go 1.25.3
 
 require (
+	filippo.io/age v1.2.1
 	github.com/blacktop/go-termimg v0.1.24
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/charmbracelet/glamour v0.10.0
@@ -14,7 +15,9 @@ require (
 	github.com/ogen-go/ogen v1.16.0
 	github.com/ras0q/bubbletree v0.0.0-20251111115524-4ade398dd740
 	github.com/ras0q/goalie v0.6.0
+	github.com/sahilm/fuzzy v0.1.1
 	github.com/traPtitech/go-traq-oauth2 v1.0.0
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/oauth2 v0.33.0
 	golang.org/x/sync v0.19.0
 )
@@ -55,7 +58,6 @@ require (
 	github.com/microcosm-cc/bluemonday v1.0.27 // indirect
 	github.com/muesli/reflow v0.3.0 // indirect
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
-	github.com/sahilm/fuzzy v0.1.1 // indirect
 	github.com/segmentio/asm v1.2.1 // indirect
 	github.com/shopspring/decimal v1.4.0 // indirect
 	github.com/soniakeys/quant v1.0.0 // indirect
@@ -68,6 +70,7 @@ require (
 	github.com/yuin/goldmark-emoji v1.0.6 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
+	golang.org/x/crypto v0.46.0 // indirect
 	golang.org/x/exp v0.0.0-20251113190631-e25ba8c21ef6 // indirect
 	golang.org/x/image v0.33.0 // indirect
 	golang.org/x/mod v0.31.0 // indirect
@@ -84,7 +87,7 @@ require (
 	github.com/charmbracelet/bubbles v0.21.0
 	github.com/charmbracelet/colorprofile v0.4.1 // indirect
 	github.com/charmbracelet/lipgloss v1.1.1-0.20250404203927-76690c660834
-	github.com/charmbracelet/x/ansi v0.11.4 // indirect
+	github.com/charmbracelet/x/ansi v0.11.4
 	github.com/charmbracelet/x/cellbuf v0.0.14 // indirect
 	github.com/charmbracelet/x/term v0.2.2 // indirect
 	github.com/erikgeiser/coninput v0.0.0-20211004153227-1c3628e74d0f // indirect
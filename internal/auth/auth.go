@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,15 +11,17 @@ import (
 	"os"
 	"path/filepath"
 
+	"filippo.io/age"
 	traqoauth2 "github.com/traPtitech/go-traq-oauth2"
 	"github.com/zalando/go-keyring"
 	"golang.org/x/oauth2"
 )
 
 var (
-	configDir, _ = os.UserConfigDir()
-	ltConfigDir  = filepath.Join(configDir, "lazytraq")
-	ltHostFile   = "hosts.json"
+	configDir, _  = os.UserConfigDir()
+	ltConfigDir   = filepath.Join(configDir, "lazytraq")
+	ltHostFile    = "hosts.json"
+	ltHostAgeFile = "hosts.json.age"
 
 	keyringUser = "lazytraq-user"
 
@@ -30,16 +33,29 @@ type TokenStore int
 const (
 	TokenStoreUnknown TokenStore = iota
 	TokenStoreKeyring
+	// TokenStoreFile is the legacy plaintext hosts.json. Tokens found here
+	// should be moved with MigrateFileToken.
 	TokenStoreFile
 	TokenStoreWeb
+	// TokenStoreEncryptedFile is hosts.json.age, encrypted with a FileKey.
+	TokenStoreEncryptedFile
 )
 
-func GetToken(ctx context.Context, apiHost string, authURLCh chan<- string) (*oauth2.Token, TokenStore, error) {
+func GetToken(ctx context.Context, apiHost string, fileKey FileKeyProvider, authURLCh chan<- string) (*oauth2.Token, TokenStore, error) {
 	token, err := getTokenFromKeyring(keyringService(apiHost), keyringUser)
 	if err == nil {
 		return token, TokenStoreKeyring, nil
 	}
 
+	token, err = getTokenFromEncryptedFile(apiHost, fileKey)
+	if err == nil {
+		return token, TokenStoreEncryptedFile, nil
+	}
+
+	if !errors.Is(err, errTokenNotFound) {
+		return nil, TokenStoreUnknown, fmt.Errorf("get token from encrypted file: %w", err)
+	}
+
 	token, err = getTokenFromFile(apiHost)
 	if err == nil {
 		return token, TokenStoreFile, nil
@@ -81,6 +97,7 @@ func getTokenFromFile(host string) (*oauth2.Token, error) {
 
 		return nil, fmt.Errorf("open file (%s/%s): %w", ltConfigDir, ltHostFile, err)
 	}
+	defer f.Close()
 
 	var tokens map[string]string
 	if err := json.NewDecoder(f).Decode(&tokens); err != nil {
@@ -97,6 +114,40 @@ func getTokenFromFile(host string) (*oauth2.Token, error) {
 	}, nil
 }
 
+func getTokenFromEncryptedFile(host string, fileKey FileKeyProvider) (*oauth2.Token, error) {
+	if fileKey == nil {
+		return nil, errTokenNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(ltConfigDir, ltHostAgeFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errTokenNotFound
+		}
+
+		return nil, fmt.Errorf("read file (%s/%s): %w", ltConfigDir, ltHostAgeFile, err)
+	}
+
+	key, err := fileKey()
+	if err != nil {
+		return nil, fmt.Errorf("get file key: %w", err)
+	}
+
+	tokens, err := decryptTokens(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt file (%s/%s): %w", ltConfigDir, ltHostAgeFile, err)
+	}
+
+	token, ok := tokens[host]
+	if !ok {
+		return nil, errTokenNotFound
+	}
+
+	return &oauth2.Token{
+		AccessToken: token,
+	}, nil
+}
+
 func getTokenFromWeb(ctx context.Context, apiHost string, authURLCh chan<- string) (*oauth2.Token, error) {
 	state := "state" // TODO: generate random state
 
@@ -153,7 +204,7 @@ func startCallbackServer(addr string) (chan string, error) {
 	return codeCh, nil
 }
 
-func SetToken(apiHost string, token *oauth2.Token) (TokenStore, error) {
+func SetToken(apiHost string, token *oauth2.Token, fileKey FileKeyProvider) (TokenStore, error) {
 	if token == nil {
 		return TokenStoreUnknown, fmt.Errorf("token is nil")
 	}
@@ -163,12 +214,27 @@ func SetToken(apiHost string, token *oauth2.Token) (TokenStore, error) {
 		return TokenStoreKeyring, nil
 	}
 
-	fileErr := setTokenToFile(apiHost, token.AccessToken)
+	fileErr := setTokenToEncryptedFile(apiHost, token.AccessToken, fileKey)
 	if fileErr == nil {
-		return TokenStoreFile, nil
+		return TokenStoreEncryptedFile, nil
+	}
+
+	return TokenStoreUnknown, fmt.Errorf("set token to keyring: %v; set token to encrypted file: %w", keyringErr, fileErr)
+}
+
+// MigrateFileToken moves a token found in the legacy plaintext hosts.json
+// to the keyring or the encrypted file, and removes the plaintext entry.
+func MigrateFileToken(apiHost string, token *oauth2.Token, fileKey FileKeyProvider) (TokenStore, error) {
+	tokenStore, err := SetToken(apiHost, token, fileKey)
+	if err != nil {
+		return TokenStoreUnknown, fmt.Errorf("set token: %w", err)
 	}
 
-	return TokenStoreUnknown, fmt.Errorf("set token to keyring: %v; set token to file: %w", keyringErr, fileErr)
+	if err := removeTokenFromFile(apiHost); err != nil {
+		return tokenStore, fmt.Errorf("remove token from file: %w", err)
+	}
+
+	return tokenStore, nil
 }
 
 func setTokenToKeyring(service, username, token string) error {
@@ -179,7 +245,16 @@ func setTokenToKeyring(service, username, token string) error {
 	return nil
 }
 
-func setTokenToFile(host, token string) error {
+func setTokenToEncryptedFile(host, token string, fileKey FileKeyProvider) error {
+	if fileKey == nil {
+		return errors.New("file key is not configured")
+	}
+
+	key, err := fileKey()
+	if err != nil {
+		return fmt.Errorf("get file key: %w", err)
+	}
+
 	if err := os.MkdirAll(ltConfigDir, 0700); err != nil {
 		return fmt.Errorf("create config dir: %w", err)
 	}
@@ -188,21 +263,66 @@ func setTokenToFile(host, token string) error {
 	if err != nil {
 		return fmt.Errorf("open config dir: %w", err)
 	}
+	defer root.Close()
 
 	tokens := map[string]string{}
-	if data, err := root.ReadFile(ltHostFile); err == nil {
-		if err := json.Unmarshal(data, &tokens); err != nil {
-			return fmt.Errorf("decode file (%s) to json: %w", ltHostFile, err)
+	if data, err := root.ReadFile(ltHostAgeFile); err == nil {
+		tokens, err = decryptTokens(data, key)
+		if err != nil {
+			return fmt.Errorf("decrypt file (%s): %w", ltHostAgeFile, err)
 		}
 	} else {
 		if !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("read file (%s): %w", ltHostFile, err)
+			return fmt.Errorf("read file (%s): %w", ltHostAgeFile, err)
 		}
 	}
 
 	tokens[host] = token
 
-	data, err := json.MarshalIndent(tokens, "", "  ")
+	data, err := encryptTokens(tokens, key)
+	if err != nil {
+		return fmt.Errorf("encrypt tokens: %w", err)
+	}
+
+	if err := root.WriteFile(ltHostAgeFile, data, 0600); err != nil {
+		return fmt.Errorf("write file (%s): %w", ltHostAgeFile, err)
+	}
+
+	return nil
+}
+
+func removeTokenFromFile(host string) error {
+	root, err := os.OpenRoot(ltConfigDir)
+	if err != nil {
+		return fmt.Errorf("open config dir: %w", err)
+	}
+	defer root.Close()
+
+	data, err := root.ReadFile(ltHostFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("read file (%s): %w", ltHostFile, err)
+	}
+
+	tokens := map[string]string{}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("decode file (%s) to json: %w", ltHostFile, err)
+	}
+
+	delete(tokens, host)
+
+	if len(tokens) == 0 {
+		if err := root.Remove(ltHostFile); err != nil {
+			return fmt.Errorf("remove file (%s): %w", ltHostFile, err)
+		}
+
+		return nil
+	}
+
+	data, err = json.MarshalIndent(tokens, "", "  ")
 	if err != nil {
 		return fmt.Errorf("encode tokens to json: %w", err)
 	}
@@ -214,6 +334,38 @@ func setTokenToFile(host, token string) error {
 	return nil
 }
 
+func encryptTokens(tokens map[string]string, key *FileKey) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, key.Recipient)
+	if err != nil {
+		return nil, fmt.Errorf("create age writer: %w", err)
+	}
+
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		return nil, fmt.Errorf("encode tokens to json: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("close age writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decryptTokens(data []byte, key *FileKey) (map[string]string, error) {
+	r, err := age.Decrypt(bytes.NewReader(data), key.Identity)
+	if err != nil {
+		return nil, fmt.Errorf("create age reader: %w", err)
+	}
+
+	tokens := map[string]string{}
+	if err := json.NewDecoder(r).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("decode tokens from json: %w", err)
+	}
+
+	return tokens, nil
+}
+
 func keyringService(apiHost string) string {
 	return fmt.Sprintf("lazytraq-%s", apiHost)
 }
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+func identityFileKey(t *testing.T) *FileKey {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+
+	identityPath := filepath.Join(t.TempDir(), "identity.txt")
+	if err := os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("write identity: %v", err)
+	}
+
+	key, err := LoadIdentityFileKey(identityPath)
+	if err != nil {
+		t.Fatalf("LoadIdentityFileKey() error = %v", err)
+	}
+
+	return key
+}
+
+func passphraseFileKey(t *testing.T, passphrase string) *FileKey {
+	t.Helper()
+
+	key, err := NewPassphraseFileKey(passphrase)
+	if err != nil {
+		t.Fatalf("NewPassphraseFileKey() error = %v", err)
+	}
+
+	return key
+}
+
+func TestEncryptDecryptTokens(t *testing.T) {
+	t.Parallel()
+
+	tokens := map[string]string{
+		"q.trap.jp":       "token",
+		"traq.example.jp": "another token",
+	}
+
+	tests := []struct {
+		name       string
+		encryptKey *FileKey
+		decryptKey *FileKey
+		wantErr    bool
+	}{
+		{
+			name:       "passphrase",
+			encryptKey: passphraseFileKey(t, "passphrase"),
+			decryptKey: passphraseFileKey(t, "passphrase"),
+		},
+		{
+			name:       "wrong passphrase",
+			encryptKey: passphraseFileKey(t, "passphrase"),
+			decryptKey: passphraseFileKey(t, "wrong"),
+			wantErr:    true,
+		},
+		{
+			name:       "X25519 identity",
+			encryptKey: identityFileKey(t),
+		},
+		{
+			name:       "other X25519 identity",
+			encryptKey: identityFileKey(t),
+			decryptKey: identityFileKey(t),
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			decryptKey := tt.decryptKey
+			if decryptKey == nil {
+				decryptKey = tt.encryptKey
+			}
+
+			data, err := encryptTokens(tokens, tt.encryptKey)
+			if err != nil {
+				t.Fatalf("encryptTokens() error = %v", err)
+			}
+
+			got, err := decryptTokens(data, decryptKey)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("decryptTokens() succeeded with the wrong key")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decryptTokens() error = %v", err)
+			}
+
+			if !maps.Equal(got, tokens) {
+				t.Errorf("decryptTokens() = %v, want %v", got, tokens)
+			}
+		})
+	}
+}
+
+// TestMigrateFileToken is not parallel, as it replaces the config dir and the keyring.
+func TestMigrateFileToken(t *testing.T) {
+	const host = "q.trap.jp"
+	key := identityFileKey(t)
+
+	configDir := ltConfigDir
+	t.Cleanup(func() {
+		ltConfigDir = configDir
+	})
+
+	tests := []struct {
+		name       string
+		keyringErr error
+		plaintext  map[string]string
+		wantStore  TokenStore
+		// wantPlaintext is nil if hosts.json should be removed.
+		wantPlaintext map[string]string
+	}{
+		{
+			name:      "to keyring",
+			plaintext: map[string]string{host: "token"},
+			wantStore: TokenStoreKeyring,
+		},
+		{
+			name:       "to encrypted file",
+			keyringErr: errors.New("keyring is unavailable"),
+			plaintext:  map[string]string{host: "token"},
+			wantStore:  TokenStoreEncryptedFile,
+		},
+		{
+			name:       "keeps other hosts",
+			keyringErr: errors.New("keyring is unavailable"),
+			plaintext: map[string]string{
+				host:              "token",
+				"traq.example.jp": "another token",
+			},
+			wantStore:     TokenStoreEncryptedFile,
+			wantPlaintext: map[string]string{"traq.example.jp": "another token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ltConfigDir = t.TempDir()
+			if tt.keyringErr != nil {
+				keyring.MockInitWithError(tt.keyringErr)
+			} else {
+				keyring.MockInit()
+			}
+
+			data, err := json.Marshal(tt.plaintext)
+			if err != nil {
+				t.Fatalf("encode hosts: %v", err)
+			}
+
+			if err := os.WriteFile(filepath.Join(ltConfigDir, ltHostFile), data, 0o600); err != nil {
+				t.Fatalf("write hosts: %v", err)
+			}
+
+			fileKey := func() (*FileKey, error) {
+				return key, nil
+			}
+
+			store, err := MigrateFileToken(host, &oauth2.Token{AccessToken: tt.plaintext[host]}, fileKey)
+			if err != nil {
+				t.Fatalf("MigrateFileToken() error = %v", err)
+			}
+
+			if store != tt.wantStore {
+				t.Errorf("MigrateFileToken() = %v, want %v", store, tt.wantStore)
+			}
+
+			token, gotStore, err := GetToken(t.Context(), host, fileKey, nil)
+			if err != nil {
+				t.Fatalf("GetToken() error = %v", err)
+			}
+
+			if gotStore != tt.wantStore || token.AccessToken != tt.plaintext[host] {
+				t.Errorf("GetToken() = %q from %v, want %q from %v", token.AccessToken, gotStore, tt.plaintext[host], tt.wantStore)
+			}
+
+			data, err = os.ReadFile(filepath.Join(ltConfigDir, ltHostFile))
+			if tt.wantPlaintext == nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("hosts.json is left: %s", data)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("read hosts: %v", err)
+			}
+
+			var got map[string]string
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("decode hosts: %v", err)
+			}
+
+			if !maps.Equal(got, tt.wantPlaintext) {
+				t.Errorf("hosts.json = %v, want %v", got, tt.wantPlaintext)
+			}
+		})
+	}
+}
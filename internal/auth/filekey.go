@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"filippo.io/age"
+)
+
+// FileKey holds the age recipient and identity used to encrypt tokens at rest.
+type FileKey struct {
+	Recipient age.Recipient
+	Identity  age.Identity
+}
+
+// FileKeyProvider returns the key for the encrypted file store.
+// It is called lazily, only when the encrypted file store is actually used.
+type FileKeyProvider func() (*FileKey, error)
+
+// NewPassphraseFileKey derives a file key from a user passphrase with scrypt.
+func NewPassphraseFileKey(passphrase string) (*FileKey, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is empty")
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("create scrypt recipient: %w", err)
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("create scrypt identity: %w", err)
+	}
+
+	return &FileKey{
+		Recipient: recipient,
+		Identity:  identity,
+	}, nil
+}
+
+// LoadIdentityFileKey reads an age X25519 identity file (as generated by age-keygen).
+func LoadIdentityFileKey(identityPath string) (*FileKey, error) {
+	f, err := os.Open(identityPath)
+	if err != nil {
+		return nil, fmt.Errorf("open identity file (%s): %w", identityPath, err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse identity file (%s): %w", identityPath, err)
+	}
+
+	for _, identity := range identities {
+		x25519Identity, ok := identity.(*age.X25519Identity)
+		if !ok {
+			continue
+		}
+
+		return &FileKey{
+			Recipient: x25519Identity.Recipient(),
+			Identity:  x25519Identity,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no X25519 identity found in %s", identityPath)
+}
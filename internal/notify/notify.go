@@ -0,0 +1,215 @@
+// Package notify alerts the user of messages that mention them or that are posted
+// in channels they are notified of, e.g. while lazytraq is in a background window.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+)
+
+// Event reports a new message.
+type Event struct {
+	ChannelID uuid.UUID
+	MessageID uuid.UUID
+	// Channel and Author describe the message in notifications, e.g. "#general" and "@alice".
+	Channel string
+	Author  string
+	// Text is the content of the message as plain text.
+	Text string
+	// Noticeable is true if traQ considers the unread messages of the channel
+	// addressed to the user, e.g. mentions and citations of their messages. It stays
+	// true until the channel is read, so it may be about an earlier message.
+	Noticeable bool
+	// WasNoticeable is true if the channel was already noticeable when the previous
+	// message was reported, so that Noticeable alone does not call for an alert.
+	WasNoticeable bool
+	// Direct is true if the message is a direct message.
+	Direct bool
+	// MentionsMe is true if the message mentions the user or one of their groups.
+	MentionsMe bool
+	// Cites is true if the message links to another message.
+	Cites bool
+}
+
+// EventSource delivers events about new messages. PollingSource polls traQ;
+// other transports such as the WebSocket, or fakes, can be used in its place.
+type EventSource interface {
+	// Run sends events until ctx is done or the source fails.
+	Run(ctx context.Context, events chan<- Event) error
+}
+
+// Preferences are the notification settings of the user on traQ.
+type Preferences interface {
+	SubscriptionLevel(ctx context.Context, channelID uuid.UUID) (traqapi.ChannelSubscribeLevel, error)
+	// NotifyCitation reports whether the user is notified when their messages are cited.
+	NotifyCitation(ctx context.Context) (bool, error)
+}
+
+// Notifier sends notifications to the sinks for the events that the preferences
+// ask for.
+type Notifier struct {
+	source EventSource
+	prefs  Preferences
+	sinks  []Sink
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+func New(source EventSource, prefs Preferences, sinks []Sink) *Notifier {
+	return &Notifier{
+		source: source,
+		prefs:  prefs,
+		sinks:  sinks,
+	}
+}
+
+// Run delivers notifications until ctx is done or the source fails.
+func (n *Notifier) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan Event)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- n.source.Run(ctx, events)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-errCh:
+			return err
+
+		case event := <-events:
+			n.handle(ctx, event)
+		}
+	}
+}
+
+// Start runs the notifier in the background until Close is called.
+// It does nothing if there are no sinks.
+func (n *Notifier) Start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.sinks) == 0 || n.stop != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.stop = cancel
+	n.done = make(chan struct{})
+
+	go func() {
+		defer close(n.done)
+
+		if err := n.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "notifications stopped", "error", err)
+		}
+	}()
+}
+
+// Close stops the notifier started by Start and waits for it to finish.
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return nil
+	}
+
+	n.stop()
+	<-n.done
+	n.stop = nil
+
+	return nil
+}
+
+func (n *Notifier) handle(ctx context.Context, event Event) {
+	ok, err := n.shouldNotify(ctx, event)
+	if err != nil {
+		slog.WarnContext(ctx, "load notification preferences", "channelID", event.ChannelID, "error", err)
+	}
+
+	if !ok {
+		return
+	}
+
+	body := event.Author + ": " + event.Text
+	if event.Noticeable && !event.WasNoticeable && !event.MentionsMe && !event.Direct && !event.Cites {
+		// only the latest message is reported, which is not the one addressed to the user
+		body = "(addressed to you earlier) " + body
+	}
+
+	notification := Notification{
+		Title: event.Channel,
+		Body:  body,
+	}
+	for _, sink := range n.sinks {
+		if err := sink.Notify(ctx, notification); err != nil {
+			slog.WarnContext(ctx, "send notification", "sink", sink.Name(), "error", err)
+		}
+	}
+}
+
+// shouldNotify notifies of messages addressed to the user, except citations when
+// the user turned them off, and of every message in channels at the notification
+// subscription level.
+func (n *Notifier) shouldNotify(ctx context.Context, event Event) (bool, error) {
+	if event.MentionsMe || event.Direct {
+		return true, nil
+	}
+
+	if event.Noticeable && !event.WasNoticeable {
+		// a message that cites without mentioning the user is noticeable because of
+		// the citation
+		if !event.Cites {
+			return true, nil
+		}
+
+		notify, err := n.prefs.NotifyCitation(ctx)
+		if err != nil || notify {
+			return notify, err
+		}
+	}
+
+	level, err := n.prefs.SubscriptionLevel(ctx, event.ChannelID)
+	if err != nil {
+		return false, err
+	}
+
+	return level == traqapi.ChannelSubscribeLevel2, nil
+}
+
+// ContextPreferences reads the preferences from the caches of traqContext.
+func ContextPreferences(traqContext *traqapiext.Context) Preferences {
+	return contextPreferences{traqContext: traqContext}
+}
+
+type contextPreferences struct {
+	traqContext *traqapiext.Context
+}
+
+func (p contextPreferences) SubscriptionLevel(ctx context.Context, channelID uuid.UUID) (traqapi.ChannelSubscribeLevel, error) {
+	levels, err := p.traqContext.ChannelSubscriptions.Get(ctx, struct{}{})
+	if err != nil {
+		return traqapi.ChannelSubscribeLevel0, err
+	}
+
+	// channels that are not subscribed to are missing
+	return levels[channelID], nil
+}
+
+func (p contextPreferences) NotifyCitation(ctx context.Context) (bool, error) {
+	return p.traqContext.NotifyCitation.Get(ctx, struct{}{})
+}
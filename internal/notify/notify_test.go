@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+var errSourceDone = errors.New("source done")
+
+// fakeSource sends its events and stops.
+type fakeSource []Event
+
+func (s fakeSource) Run(ctx context.Context, events chan<- Event) error {
+	for _, event := range s {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return errSourceDone
+}
+
+type fakePreferences struct {
+	levels         map[uuid.UUID]traqapi.ChannelSubscribeLevel
+	notifyCitation bool
+}
+
+func (p fakePreferences) SubscriptionLevel(_ context.Context, channelID uuid.UUID) (traqapi.ChannelSubscribeLevel, error) {
+	return p.levels[channelID], nil
+}
+
+func (p fakePreferences) NotifyCitation(context.Context) (bool, error) {
+	return p.notifyCitation, nil
+}
+
+// recordingSink records the notifications it is sent.
+type recordingSink struct {
+	notifications []Notification
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Notify(_ context.Context, notification Notification) error {
+	s.notifications = append(s.notifications, notification)
+	return nil
+}
+
+func TestNotifierRun(t *testing.T) {
+	t.Parallel()
+
+	notifiedID, subscribedID := uuid.New(), uuid.New()
+	prefs := fakePreferences{
+		levels: map[uuid.UUID]traqapi.ChannelSubscribeLevel{
+			notifiedID:   traqapi.ChannelSubscribeLevel2,
+			subscribedID: traqapi.ChannelSubscribeLevel1,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		prefs  fakePreferences
+		events []Event
+		want   []string
+	}{
+		{
+			name: "mention",
+			events: []Event{
+				{ChannelID: subscribedID, Author: "@alice", Text: "hi", Noticeable: true, MentionsMe: true},
+			},
+			want: []string{"@alice: hi"},
+		},
+		{
+			name: "citation with NotifyCitation off",
+			events: []Event{
+				{ChannelID: subscribedID, Author: "@alice", Text: "see this", Noticeable: true, Cites: true},
+			},
+		},
+		{
+			name: "citation with NotifyCitation on",
+			prefs: fakePreferences{
+				levels:         prefs.levels,
+				notifyCitation: true,
+			},
+			events: []Event{
+				{ChannelID: subscribedID, Author: "@alice", Text: "see this", Noticeable: true, Cites: true},
+			},
+			want: []string{"@alice: see this"},
+		},
+		{
+			name: "citation mentioning someone else",
+			events: []Event{
+				{ChannelID: subscribedID, Author: "@alice", Text: "@bob see this", Noticeable: true, Cites: true},
+			},
+		},
+		{
+			name: "subscription levels",
+			events: []Event{
+				{ChannelID: notifiedID, Author: "@alice", Text: "notified"},
+				{ChannelID: subscribedID, Author: "@alice", Text: "subscribed"},
+			},
+			want: []string{"@alice: notified"},
+		},
+		{
+			name: "noticeable until read",
+			events: []Event{
+				{ChannelID: subscribedID, Author: "@alice", Text: "hi", Noticeable: true, MentionsMe: true},
+				{ChannelID: subscribedID, Author: "@bob", Text: "unrelated", Noticeable: true, WasNoticeable: true},
+				{ChannelID: subscribedID, Author: "@alice", Text: "again", Noticeable: true, WasNoticeable: true, MentionsMe: true},
+			},
+			want: []string{"@alice: hi", "@alice: again"},
+		},
+		{
+			name: "noticeable because of an earlier message",
+			events: []Event{
+				{ChannelID: subscribedID, Author: "@bob", Text: "latest", Noticeable: true},
+			},
+			want: []string{"(addressed to you earlier) @bob: latest"},
+		},
+		{
+			name: "direct message",
+			events: []Event{
+				{ChannelID: uuid.New(), Author: "@alice", Text: "hi", Noticeable: true, Direct: true},
+				{ChannelID: uuid.New(), Author: "@alice", Text: "hi again", Noticeable: true, WasNoticeable: true, Direct: true},
+			},
+			want: []string{"@alice: hi", "@alice: hi again"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.prefs.levels == nil {
+				tt.prefs.levels = prefs.levels
+			}
+
+			sink := &recordingSink{}
+			n := New(fakeSource(tt.events), tt.prefs, []Sink{sink})
+			if err := n.Run(context.Background()); !errors.Is(err, errSourceDone) {
+				t.Fatalf("Run() error = %v, want %v", err, errSourceDone)
+			}
+
+			got := make([]string, 0, len(sink.notifications))
+			for _, notification := range sink.notifications {
+				got = append(got, notification.Body)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("notified %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// Notification is what a sink shows for an event.
+type Notification struct {
+	Title string
+	Body  string
+}
+
+// Sink shows notifications to the user.
+type Sink interface {
+	// Name identifies the sink in logs and in ParseSinks.
+	Name() string
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// ParseSinks creates the sinks named in a comma separated list:
+//
+//	bell         ring the terminal bell, which tmux shows on the window
+//	osc9         OSC 9 desktop notifications (iTerm2, WezTerm, Windows Terminal, ...)
+//	osc777       OSC 777 desktop notifications (urxvt, foot, Ghostty, ...)
+//	notify-send  run notify-send
+//	none         no notifications
+//
+// An empty spec means bell. Terminal sinks pass their escape sequences to term.
+// Inside tmux, escape sequences are wrapped so that tmux passes them to the outer
+// terminal, which needs allow-passthrough to be on.
+func ParseSinks(spec string, term *Terminal) ([]Sink, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = "bell"
+	}
+
+	sinks := make([]Sink, 0)
+	for _, name := range strings.Split(spec, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "bell":
+			sinks = append(sinks, bellSink{term: term})
+		case "osc9":
+			sinks = append(sinks, osc9Sink{term: term})
+		case "osc777":
+			sinks = append(sinks, osc777Sink{term: term})
+		case "notify-send":
+			sinks = append(sinks, commandSink{name: "notify-send"})
+		case "none":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unknown notification sink: %s", name)
+		}
+	}
+
+	return sinks, nil
+}
+
+// Terminal passes the escape sequences of the terminal sinks to the TUI, which
+// draws the screen on the same terminal. The TUI writes them with the screen, so
+// that they do not break up the sequences it draws with.
+type Terminal struct {
+	sequences chan string
+	tmux      bool
+}
+
+func NewTerminal() *Terminal {
+	return &Terminal{
+		sequences: make(chan string),
+		tmux:      os.Getenv("TMUX") != "",
+	}
+}
+
+// Sequences returns the escape sequences to write to the terminal.
+func (t *Terminal) Sequences() <-chan string {
+	return t.sequences
+}
+
+func (t *Terminal) write(ctx context.Context, sequence string) error {
+	select {
+	case t.sequences <- sequence:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeOSC writes an operating system command, wrapped for tmux if needed.
+func (t *Terminal) writeOSC(ctx context.Context, command string) error {
+	sequence := "\x1b]" + command + "\x07"
+	if t.tmux {
+		sequence = "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+
+	return t.write(ctx, sequence)
+}
+
+type bellSink struct {
+	term *Terminal
+}
+
+func (s bellSink) Name() string { return "bell" }
+
+func (s bellSink) Notify(ctx context.Context, _ Notification) error {
+	return s.term.write(ctx, "\a")
+}
+
+type osc9Sink struct {
+	term *Terminal
+}
+
+func (s osc9Sink) Name() string { return "osc9" }
+
+func (s osc9Sink) Notify(ctx context.Context, notification Notification) error {
+	return s.term.writeOSC(ctx, "9;"+sanitize(notification.Title+": "+notification.Body))
+}
+
+type osc777Sink struct {
+	term *Terminal
+}
+
+func (s osc777Sink) Name() string { return "osc777" }
+
+func (s osc777Sink) Notify(ctx context.Context, notification Notification) error {
+	// the title must not contain the separator of the fields
+	title := strings.ReplaceAll(sanitize(notification.Title), ";", ",")
+	return s.term.writeOSC(ctx, "777;notify;"+title+";"+sanitize(notification.Body))
+}
+
+type commandSink struct {
+	name string
+}
+
+func (s commandSink) Name() string { return s.name }
+
+func (s commandSink) Notify(ctx context.Context, notification Notification) error {
+	cmd := exec.CommandContext(ctx, s.name, "--app-name=lazytraq", notification.Title, notification.Body)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("run %s: %w: %s", s.name, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// sanitize removes control characters, which would end escape sequences early.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+
+		return r
+	}, s)
+}
@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+)
+
+const (
+	// PollInterval is how often PollingSource checks for new messages.
+	PollInterval = 20 * time.Second
+	// maxTextLength bounds the length of the message text in notifications.
+	maxTextLength = 200
+)
+
+// PollingSource finds new messages by polling the unread channels of the user,
+// as lazytraq does not use the WebSocket of traQ yet. Only the latest message of
+// a channel is reported, even if several arrived between polls.
+type PollingSource struct {
+	traqContext *traqapiext.Context
+	interval    time.Duration
+}
+
+var _ EventSource = (*PollingSource)(nil)
+
+func NewPollingSource(traqContext *traqapiext.Context, interval time.Duration) *PollingSource {
+	return &PollingSource{
+		traqContext: traqContext,
+		interval:    interval,
+	}
+}
+
+// Run reports the channels whose unread messages were updated since the previous
+// poll. Messages unread before the first poll are not reported.
+func (s *PollingSource) Run(ctx context.Context, events chan<- Event) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	// seen holds the unread state of each channel at the previous poll
+	var seen map[uuid.UUID]unreadState
+	for {
+		unreads, err := s.traqContext.UnreadChannels(ctx)
+		switch {
+		case err == nil:
+			current := make(map[uuid.UUID]unreadState, len(unreads))
+			for _, unread := range unreads {
+				channelID := unread.GetChannelId()
+				state := unreadState{
+					updatedAt:  unread.GetUpdatedAt(),
+					noticeable: unread.GetNoticeable(),
+				}
+				current[channelID] = state
+
+				last, ok := seen[channelID]
+				if seen == nil || (ok && !state.updatedAt.After(last.updatedAt)) {
+					continue
+				}
+
+				event, err := s.event(ctx, channelID)
+				if err != nil {
+					slog.WarnContext(ctx, "load new message for notification", "channelID", channelID, "error", err)
+					continue
+				}
+
+				event.Noticeable = state.noticeable
+				// channels that were read since are missing from seen
+				event.WasNoticeable = last.noticeable
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			seen = current
+
+		case errors.Is(err, traqapiext.ErrOffline):
+			// polled again once traQ is reachable
+
+		default:
+			slog.WarnContext(ctx, "poll unread channels", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// unreadState is what PollingSource remembers of an unread channel.
+type unreadState struct {
+	updatedAt  time.Time
+	noticeable bool
+}
+
+// event describes the latest message of the channel, leaving the unread state to Run.
+func (s *PollingSource) event(ctx context.Context, channelID uuid.UUID) (Event, error) {
+	message, err := s.traqContext.LatestMessage(ctx, channelID)
+	if err != nil {
+		return Event{}, err
+	}
+
+	me, err := s.traqContext.Me.Get(ctx, struct{}{})
+	if err != nil {
+		return Event{}, err
+	}
+
+	// channels other than the public ones are direct messages
+	if _, err := s.traqContext.Channels.Get(ctx, struct{}{}); err != nil {
+		return Event{}, err
+	}
+
+	channel := "direct message"
+	path, ok := s.traqContext.ChannelPath(channelID)
+	if ok {
+		channel = "#" + path
+	}
+
+	author := "unknown"
+	if user, ok := s.traqContext.UserByID(message.GetUserId()); ok {
+		author = user.GetName()
+	}
+
+	md := s.traqContext.ParseMarkdown(message.GetContent(), traqapiext.MarkdownOptions{})
+	mentionsMe := slices.ContainsFunc(md.Tokens, func(token traqapiext.MarkdownToken) bool {
+		switch token.Kind {
+		case traqapiext.TokenUser:
+			return token.ID == me.GetID()
+		case traqapiext.TokenGroup:
+			return slices.Contains(me.GetGroups(), token.ID)
+		default:
+			return false
+		}
+	})
+
+	text := []rune(strings.Join(strings.Fields(md.Content), " "))
+	if len(text) > maxTextLength {
+		text = append(text[:maxTextLength-1], '…')
+	}
+
+	return Event{
+		ChannelID:  channelID,
+		MessageID:  message.GetID(),
+		Channel:    channel,
+		Author:     "@" + author,
+		Text:       cmp.Or(string(text), "(no text)"),
+		Direct:     !ok,
+		MentionsMe: mentionsMe,
+		Cites:      len(s.traqContext.FindMessageLinks(message.GetContent())) > 0,
+	}, nil
+}
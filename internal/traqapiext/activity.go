@@ -0,0 +1,52 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+// activityTimelineLimit is the number of messages fetched for the activity timeline.
+const activityTimelineLimit = 50
+
+// ActivityQuery selects the messages in the activity timeline.
+type ActivityQuery struct {
+	// All includes the channels the user is not subscribed to.
+	All bool
+	// PerChannel keeps only the latest message of each channel.
+	PerChannel bool
+}
+
+func newActivityStore(traqClient *traqapi.Client) (*sc.Cache[ActivityQuery, []traqapi.ActivityTimelineMessage], error) {
+	// short, as the timeline is polled for new messages
+	freshFor := time.Second * 10
+	ttl := time.Minute * 5
+
+	return sc.New(func(ctx context.Context, query ActivityQuery) (messages []traqapi.ActivityTimelineMessage, err error) {
+		defer wrapf(&err, "get activity timeline from traQ")
+
+		res, err := traqClient.GetActivityTimeline(ctx, traqapi.GetActivityTimelineParams{
+			Limit:      traqapi.NewOptInt(activityTimelineLimit),
+			All:        traqapi.NewOptBool(query.All),
+			PerChannel: traqapi.NewOptBool(query.PerChannel),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.GetActivityTimelineOKApplicationJSON:
+			return *res, nil
+
+		case *traqapi.GetActivityTimelineBadRequest:
+			return nil, errors.New("bad request")
+
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
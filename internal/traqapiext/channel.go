@@ -2,8 +2,13 @@ package traqapiext
 
 import (
 	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"iter"
+	"regexp"
 	"sort"
+	"strings"
 	"sync/atomic"
 
 	"github.com/google/uuid"
@@ -15,6 +20,8 @@ type ChannelNode struct {
 	Channel    traqapi.Channel
 	ChildNodes []*ChannelNode
 	IsOpen     atomic.Bool
+	// HasDraft marks channels with an unsent message.
+	HasDraft bool
 }
 
 var _ bubbletree.Node[uuid.UUID] = (*ChannelNode)(nil)
@@ -35,7 +42,12 @@ func (m *ChannelNode) Content() string {
 		}
 	}
 
-	return prefix + cmp.Or(m.Channel.GetName(), ".")
+	content := prefix + cmp.Or(m.Channel.GetName(), ".")
+	if m.HasDraft {
+		content += " ✎"
+	}
+
+	return content
 }
 
 // Children implements bubbletree.Tree.
@@ -93,6 +105,25 @@ func (m *ChannelNode) Search(id uuid.UUID) (*ChannelNode, bool) {
 	return nil, false
 }
 
+// CopyOpenState opens the nodes that are open in other, matched by channel ID.
+func (m *ChannelNode) CopyOpenState(other *ChannelNode) {
+	for _, child := range m.ChildNodes {
+		if otherChild, ok := other.Search(child.ID()); ok {
+			child.IsOpen.Store(otherChild.IsOpen.Load())
+		}
+
+		child.CopyOpenState(other)
+	}
+}
+
+// MarkDrafts sets HasDraft of the nodes whose channels are in channelIDs and clears the others.
+func (m *ChannelNode) MarkDrafts(channelIDs map[uuid.UUID]struct{}) {
+	for _, child := range m.ChildNodes {
+		_, child.HasDraft = channelIDs[child.ID()]
+		child.MarkDrafts(channelIDs)
+	}
+}
+
 func ConstructTree(channels []traqapi.Channel) *ChannelNode {
 	channelMap := make(map[uuid.UUID]*ChannelNode)
 	var roots []*ChannelNode
@@ -129,3 +160,113 @@ func ConstructTree(channels []traqapi.Channel) *ChannelNode {
 
 	return &node
 }
+
+// channelNamePattern is the rule of traQ for channel names.
+var channelNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,20}$`)
+
+// ValidateChannelName checks a channel name against the rules of traQ.
+func ValidateChannelName(name string) error {
+	if !channelNamePattern.MatchString(name) {
+		return errors.New("channel names are 1-20 letters, digits, - or _")
+	}
+
+	return nil
+}
+
+// HasChildNamed reports whether the node has a child channel with the name.
+// Names are compared ignoring case, as traQ does.
+func (m *ChannelNode) HasChildNamed(name string) bool {
+	for _, child := range m.ChildNodes {
+		if strings.EqualFold(child.Channel.GetName(), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateChannel creates a channel under the parent, or at the root if parentID is uuid.Nil.
+func (c *Context) CreateChannel(ctx context.Context, name string, parentID uuid.UUID) (channel *traqapi.Channel, err error) {
+	if c.IsOffline() {
+		return nil, ErrOffline
+	}
+
+	defer wrapf(&err, "create channel %s", name)
+
+	parent := traqapi.NilUUID{Null: true}
+	if parentID != uuid.Nil {
+		parent = traqapi.NewNilUUID(parentID)
+	}
+
+	res, err := c.client.CreateChannel(ctx, traqapi.NewOptPostChannelRequest(traqapi.PostChannelRequest{
+		Name:   name,
+		Parent: parent,
+	}))
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return nil, err
+	}
+
+	switch res := res.(type) {
+	case *traqapi.Channel:
+		c.Channels.Forget(struct{}{})
+		return res, nil
+
+	case *traqapi.CreateChannelBadRequest:
+		return nil, errors.New("bad request")
+
+	case *traqapi.CreateChannelConflict:
+		return nil, errors.New("a channel with the same name already exists")
+
+	case *traqapi.CreateChannelForbidden:
+		return nil, errors.New("forbidden")
+
+	default:
+		return nil, fmt.Errorf("unreachable error")
+	}
+}
+
+// EditChannel changes the name, parent, archived state or force flag of a channel.
+// A parent of uuid.Nil moves the channel to the root.
+func (c *Context) EditChannel(ctx context.Context, channelID uuid.UUID, request traqapi.PatchChannelRequest) (err error) {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	defer wrapf(&err, "edit channel %s", channelID)
+
+	res, err := c.client.EditChannel(ctx, traqapi.NewOptPatchChannelRequest(request), traqapi.EditChannelParams{
+		ChannelId: channelID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return err
+	}
+
+	switch res.(type) {
+	case *traqapi.EditChannelNoContent:
+		c.Channels.Forget(struct{}{})
+		return nil
+
+	case *traqapi.EditChannelBadRequest:
+		return errors.New("bad request")
+
+	case *traqapi.EditChannelConflict:
+		return errors.New("a channel with the same name already exists")
+
+	case *traqapi.EditChannelForbidden:
+		return errors.New("forbidden")
+
+	case *traqapi.EditChannelNotFound:
+		return errors.New("not found")
+
+	default:
+		return fmt.Errorf("unreachable error")
+	}
+}
@@ -0,0 +1,165 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+// ChannelInfo holds the details of a channel shown in the channel info panel.
+type ChannelInfo struct {
+	Stats traqapi.ChannelStats
+	// Subscribers is nil if they cannot be listed, e.g. in forced channels.
+	Subscribers []uuid.UUID
+	Viewers     []traqapi.ChannelViewer
+	Bots        []traqapi.BotUser
+}
+
+func newChannelTopicsStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, string], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, channelID uuid.UUID) (topic string, err error) {
+		defer wrapf(&err, "get topic from traQ for channel %s", channelID)
+
+		res, err := traqClient.GetChannelTopic(ctx, traqapi.GetChannelTopicParams{
+			ChannelId: channelID,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.ChannelTopic:
+			return res.GetTopic(), nil
+
+		case *traqapi.GetChannelTopicNotFound:
+			return "", errors.New("not found")
+
+		default:
+			return "", fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
+
+func newChannelInfosStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, *ChannelInfo], error) {
+	// short, as viewers come and go
+	freshFor := time.Second * 30
+	ttl := time.Minute * 5
+
+	return sc.New(func(ctx context.Context, channelID uuid.UUID) (info *ChannelInfo, err error) {
+		defer wrapf(&err, "get channel info from traQ for channel %s", channelID)
+
+		info = &ChannelInfo{}
+
+		statsRes, err := traqClient.GetChannelStats(ctx, traqapi.GetChannelStatsParams{
+			ChannelId: channelID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := statsRes.(type) {
+		case *traqapi.ChannelStats:
+			info.Stats = *res
+		case *traqapi.GetChannelStatsNotFound:
+			return nil, errors.New("stats: not found")
+		default:
+			return nil, fmt.Errorf("stats: unreachable error")
+		}
+
+		subscribersRes, err := traqClient.GetChannelSubscribers(ctx, traqapi.GetChannelSubscribersParams{
+			ChannelId: channelID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := subscribersRes.(type) {
+		case *traqapi.GetChannelSubscribersOKApplicationJSON:
+			info.Subscribers = *res
+		case *traqapi.GetChannelSubscribersForbidden:
+			// forced channels and DMs have no subscriptions to list
+		case *traqapi.GetChannelSubscribersNotFound:
+			return nil, errors.New("subscribers: not found")
+		default:
+			return nil, fmt.Errorf("subscribers: unreachable error")
+		}
+
+		viewersRes, err := traqClient.GetChannelViewers(ctx, traqapi.GetChannelViewersParams{
+			ChannelId: channelID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := viewersRes.(type) {
+		case *traqapi.GetChannelViewersOKApplicationJSON:
+			info.Viewers = *res
+		case *traqapi.GetChannelViewersNotFound:
+			return nil, errors.New("viewers: not found")
+		default:
+			return nil, fmt.Errorf("viewers: unreachable error")
+		}
+
+		botsRes, err := traqClient.GetChannelBots(ctx, traqapi.GetChannelBotsParams{
+			ChannelId: channelID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := botsRes.(type) {
+		case *traqapi.GetChannelBotsOKApplicationJSON:
+			info.Bots = *res
+		case *traqapi.GetChannelBotsNotFound:
+			return nil, errors.New("bots: not found")
+		default:
+			return nil, fmt.Errorf("bots: unreachable error")
+		}
+
+		return info, nil
+	}, freshFor, ttl)
+}
+
+// EditChannelTopic replaces the topic of a channel.
+func (c *Context) EditChannelTopic(ctx context.Context, channelID uuid.UUID, topic string) (err error) {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	defer wrapf(&err, "edit topic of channel %s", channelID)
+
+	res, err := c.client.EditChannelTopic(ctx, traqapi.NewOptPutChannelTopicRequest(traqapi.PutChannelTopicRequest{
+		Topic: topic,
+	}), traqapi.EditChannelTopicParams{
+		ChannelId: channelID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return err
+	}
+
+	switch res.(type) {
+	case *traqapi.EditChannelTopicNoContent:
+		c.ChannelTopics.Forget(channelID)
+		return nil
+
+	case *traqapi.EditChannelTopicBadRequest:
+		return errors.New("bad request")
+
+	case *traqapi.EditChannelTopicNotFound:
+		return errors.New("not found")
+
+	default:
+		return fmt.Errorf("unreachable error")
+	}
+}
@@ -0,0 +1,236 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+// clipsLimit is the number of clipped messages fetched per folder.
+const clipsLimit = 200
+
+func newClipFoldersStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.ClipFolder], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, _ struct{}) (folders []traqapi.ClipFolder, err error) {
+		defer wrapf(&err, "get clip folders from traQ")
+
+		folders, err = traqClient.GetClipFolders(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return folders, nil
+	}, freshFor, ttl)
+}
+
+func newClipsStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, []traqapi.ClippedMessage], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, folderID uuid.UUID) (clips []traqapi.ClippedMessage, err error) {
+		defer wrapf(&err, "get clips from traQ for folder %s", folderID)
+
+		res, err := traqClient.GetClips(ctx, traqapi.GetClipsParams{
+			FolderId: folderID,
+			Limit:    traqapi.NewOptInt(clipsLimit),
+			Order:    traqapi.NewOptOrderInQuery(traqapi.OrderInQueryDesc),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.GetClipsOKApplicationJSON:
+			return *res, nil
+
+		case *traqapi.GetClipsNotFound:
+			return nil, errors.New("not found")
+
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
+
+// CreateClipFolder creates a clip folder.
+func (c *Context) CreateClipFolder(ctx context.Context, name, description string) (folder *traqapi.ClipFolder, err error) {
+	if c.IsOffline() {
+		return nil, ErrOffline
+	}
+
+	defer wrapf(&err, "create clip folder %q", name)
+
+	res, err := c.client.CreateClipFolder(ctx, traqapi.NewOptPostClipFolderRequest(traqapi.PostClipFolderRequest{
+		Name:        name,
+		Description: description,
+	}))
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return nil, err
+	}
+
+	switch res := res.(type) {
+	case *traqapi.ClipFolder:
+		c.ClipFolders.Forget(struct{}{})
+		return res, nil
+
+	case *traqapi.CreateClipFolderBadRequest:
+		return nil, errors.New("bad request")
+
+	default:
+		return nil, fmt.Errorf("unreachable error")
+	}
+}
+
+// RenameClipFolder changes the name of a clip folder.
+func (c *Context) RenameClipFolder(ctx context.Context, folderID uuid.UUID, name string) (err error) {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	defer wrapf(&err, "rename clip folder %s", folderID)
+
+	res, err := c.client.EditClipFolder(ctx, traqapi.NewOptPatchClipFolderRequest(traqapi.PatchClipFolderRequest{
+		Name: traqapi.NewOptString(name),
+	}), traqapi.EditClipFolderParams{
+		FolderId: folderID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return err
+	}
+
+	switch res.(type) {
+	case *traqapi.EditClipFolderNoContent:
+		c.ClipFolders.Forget(struct{}{})
+		return nil
+
+	case *traqapi.EditClipFolderBadRequest:
+		return errors.New("bad request")
+
+	case *traqapi.EditClipFolderNotFound:
+		return errors.New("not found")
+
+	default:
+		return fmt.Errorf("unreachable error")
+	}
+}
+
+// DeleteClipFolder deletes a clip folder. The clipped messages are kept.
+func (c *Context) DeleteClipFolder(ctx context.Context, folderID uuid.UUID) (err error) {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	defer wrapf(&err, "delete clip folder %s", folderID)
+
+	res, err := c.client.DeleteClipFolder(ctx, traqapi.DeleteClipFolderParams{
+		FolderId: folderID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return err
+	}
+
+	switch res.(type) {
+	case *traqapi.DeleteClipFolderNoContent:
+		c.ClipFolders.Forget(struct{}{})
+		c.Clips.Forget(folderID)
+		return nil
+
+	case *traqapi.DeleteClipFolderNotFound:
+		return errors.New("not found")
+
+	default:
+		return fmt.Errorf("unreachable error")
+	}
+}
+
+// ClipMessage adds a message to a clip folder.
+func (c *Context) ClipMessage(ctx context.Context, folderID, messageID uuid.UUID) (err error) {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	defer wrapf(&err, "clip message %s", messageID)
+
+	res, err := c.client.ClipMessage(ctx, traqapi.NewOptPostClipFolderMessageRequest(traqapi.PostClipFolderMessageRequest{
+		MessageId: messageID,
+	}), traqapi.ClipMessageParams{
+		FolderId: folderID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return err
+	}
+
+	switch res.(type) {
+	case *traqapi.ClippedMessage:
+		c.Clips.Forget(folderID)
+		return nil
+
+	case *traqapi.ClipMessageBadRequest:
+		return errors.New("bad request")
+
+	case *traqapi.ClipMessageConflict:
+		return errors.New("already clipped in this folder")
+
+	case *traqapi.ClipMessageNotFound:
+		return errors.New("not found")
+
+	default:
+		return fmt.Errorf("unreachable error")
+	}
+}
+
+// UnclipMessage removes a message from a clip folder.
+func (c *Context) UnclipMessage(ctx context.Context, folderID, messageID uuid.UUID) (err error) {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	defer wrapf(&err, "unclip message %s", messageID)
+
+	res, err := c.client.UnclipMessage(ctx, traqapi.UnclipMessageParams{
+		FolderId:  folderID,
+		MessageId: messageID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return err
+	}
+
+	switch res.(type) {
+	case *traqapi.UnclipMessageNoContent:
+		c.Clips.Forget(folderID)
+		return nil
+
+	case *traqapi.UnclipMessageNotFound:
+		return errors.New("not found")
+
+	default:
+		return fmt.Errorf("unreachable error")
+	}
+}
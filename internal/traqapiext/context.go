@@ -11,14 +11,16 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
-	"path"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/motoki317/sc"
-	"github.com/ras0q/goalie"
 	"github.com/ras0q/lazytraq/internal/traqapi"
 	"github.com/tdewolff/canvas"
 	"github.com/tdewolff/canvas/renderers/rasterizer"
@@ -49,19 +51,62 @@ func init() {
 }
 
 type Context struct {
-	apiHost string
-	client  *traqapi.Client
-
-	Messages    *sc.Cache[uuid.UUID, []traqapi.Message]
-	Users       *sc.Cache[struct{}, []traqapi.User]
-	Stamps      *sc.Cache[struct{}, []traqapi.StampWithThumbnail]
-	StampImages *sc.Cache[uuid.UUID, image.Image]
-	Channels    *sc.Cache[struct{}, *traqapi.ChannelList]
-	Me          *sc.Cache[struct{}, *traqapi.MyUserDetail]
+	apiHost            string
+	client             *traqapi.Client
+	messageLinkPattern *regexp.Regexp
+	// storeMu guards store, which is swapped by SwitchHost and closed by Close
+	// while values may still be revalidated in the background.
+	storeMu       sync.RWMutex
+	store         *DiskStore
+	offline       atomic.Bool
+	revalidations chan Revalidation
+	// localMessages holds message lists updated locally, served by the next load
+	// of the Messages cache instead of fetching them.
+	localMessages sync.Map // map[uuid.UUID][]traqapi.Message
+
+	usersByID      lookupIndex[traqapi.User, uuid.UUID, traqapi.User]
+	usersByName    lookupIndex[traqapi.User, string, traqapi.User]
+	stampsByName   lookupIndex[traqapi.StampWithThumbnail, string, traqapi.StampWithThumbnail]
+	stampsByID     lookupIndex[traqapi.StampWithThumbnail, uuid.UUID, traqapi.StampWithThumbnail]
+	channelPaths   lookupIndex[traqapi.ChannelList, uuid.UUID, string]
+	channelsByPath lookupIndex[traqapi.ChannelList, string, uuid.UUID]
+	channelsByID   lookupIndex[traqapi.ChannelList, uuid.UUID, traqapi.Channel]
+
+	Messages *sc.Cache[uuid.UUID, []traqapi.Message]
+	// CitedMessages holds single messages quoted by message links.
+	CitedMessages *sc.Cache[uuid.UUID, *traqapi.Message]
+	Users         *sc.Cache[struct{}, []traqapi.User]
+	UserGroups    *sc.Cache[struct{}, []traqapi.UserGroup]
+	Stamps        *sc.Cache[struct{}, []traqapi.StampWithThumbnail]
+	StampImages   *sc.Cache[uuid.UUID, image.Image]
+	Channels      *sc.Cache[struct{}, *traqapi.ChannelList]
+	Ogps          *sc.Cache[string, *traqapi.Ogp]
+	OgpImages     *sc.Cache[string, image.Image]
+	Me            *sc.Cache[struct{}, *traqapi.MyUserDetail]
+	Activity      *sc.Cache[ActivityQuery, []traqapi.ActivityTimelineMessage]
+	Pins          *sc.Cache[uuid.UUID, []traqapi.Pin]
+	ClipFolders   *sc.Cache[struct{}, []traqapi.ClipFolder]
+	// Clips holds the clipped messages of each folder, latest clip first.
+	Clips         *sc.Cache[uuid.UUID, []traqapi.ClippedMessage]
+	ChannelTopics *sc.Cache[uuid.UUID, string]
+	ChannelInfos  *sc.Cache[uuid.UUID, *ChannelInfo]
+	UserDetails   *sc.Cache[uuid.UUID, *traqapi.UserDetail]
+	UserStats     *sc.Cache[uuid.UUID, *traqapi.UserStats]
+	UserIcons     *sc.Cache[uuid.UUID, image.Image]
+	OnlineUsers   *sc.Cache[struct{}, map[uuid.UUID]struct{}]
+	// ChannelSubscriptions holds the subscription level of the channels the user subscribes to.
+	ChannelSubscriptions *sc.Cache[struct{}, map[uuid.UUID]traqapi.ChannelSubscribeLevel]
+	NotifyCitation       *sc.Cache[struct{}, bool]
+
+	Drafts *DraftStore
+	Outbox *Outbox
 }
 
 func NewContext(apiHost string, securitySource *SecuritySource) (*Context, error) {
-	c := new(Context)
+	c := &Context{
+		revalidations: make(chan Revalidation, 16),
+	}
+	c.Outbox = newOutbox(c)
 	if err := c.SwitchHost(apiHost, securitySource); err != nil {
 		return nil, fmt.Errorf("switch host: %w", err)
 	}
@@ -81,43 +126,241 @@ func (c *Context) SwitchHost(apiHost string, securitySource *SecuritySource) err
 		return fmt.Errorf("create traq client: %w", err)
 	}
 
+	if err := c.swapStore(apiHost); err != nil {
+		return err
+	}
+
+	drafts, err := OpenDraftStore(apiHost)
+	if err != nil {
+		slog.Warn("drafts are kept in memory only", "apiHost", apiHost, "error", err)
+		drafts = newMemoryDraftStore()
+	}
+
 	c.apiHost = apiHost
 	c.client = traqClient
+	c.messageLinkPattern = newMessageLinkPattern(apiHost)
+	c.Drafts = drafts
+	c.offline.Store(false)
 
-	c.Messages, err = newMessagesStore(traqClient)
+	c.Messages, err = newMessagesStore(c, traqClient)
 	if err != nil {
 		return fmt.Errorf("create messages store: %w", err)
 	}
 
-	c.Users, err = newUsersStore(traqClient)
+	c.CitedMessages, err = newCitedMessagesStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create cited messages store: %w", err)
+	}
+
+	c.Users, err = newUsersStore(c, traqClient)
 	if err != nil {
 		return fmt.Errorf("create users store: %w", err)
 	}
 
-	c.Stamps, err = newStampsStore(traqClient)
+	c.UserGroups, err = newUserGroupsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create user groups store: %w", err)
+	}
+
+	c.Stamps, err = newStampsStore(c, traqClient)
 	if err != nil {
 		return fmt.Errorf("create stamps store: %w", err)
 	}
 
-	c.StampImages, err = newStampImagesStore(traqClient)
+	stampImageStore, err := NewStampImageStore(DefaultStampImageCacheSize)
+	if err != nil {
+		return fmt.Errorf("create stamp image disk store: %w", err)
+	}
+
+	c.StampImages, err = newStampImagesStore(c, traqClient, stampImageStore)
 	if err != nil {
 		return fmt.Errorf("create stamp images store: %w", err)
 	}
 
-	c.Channels, err = newChannelsStore(traqClient)
+	c.Channels, err = newChannelsStore(c, traqClient)
 	if err != nil {
 		return fmt.Errorf("create channels store: %w", err)
 	}
 
+	c.Ogps, err = newOgpsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create ogps store: %w", err)
+	}
+
+	c.OgpImages, err = newOgpImagesStore(httpClient)
+	if err != nil {
+		return fmt.Errorf("create ogp images store: %w", err)
+	}
+
 	c.Me, err = newMeStore(traqClient)
 	if err != nil {
 		return fmt.Errorf("create me store: %w", err)
 	}
 
+	c.Activity, err = newActivityStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create activity store: %w", err)
+	}
+
+	c.Pins, err = newPinsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create pins store: %w", err)
+	}
+
+	c.ClipFolders, err = newClipFoldersStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create clip folders store: %w", err)
+	}
+
+	c.Clips, err = newClipsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create clips store: %w", err)
+	}
+
+	c.ChannelTopics, err = newChannelTopicsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create channel topics store: %w", err)
+	}
+
+	c.ChannelInfos, err = newChannelInfosStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create channel infos store: %w", err)
+	}
+
+	c.UserDetails, err = newUserDetailsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create user details store: %w", err)
+	}
+
+	c.UserStats, err = newUserStatsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create user stats store: %w", err)
+	}
+
+	c.UserIcons, err = newUserIconsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create user icons store: %w", err)
+	}
+
+	c.OnlineUsers, err = newOnlineUsersStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create online users store: %w", err)
+	}
+
+	c.ChannelSubscriptions, err = newChannelSubscriptionsStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create channel subscriptions store: %w", err)
+	}
+
+	c.NotifyCitation, err = newNotifyCitationStore(traqClient)
+	if err != nil {
+		return fmt.Errorf("create notify citation store: %w", err)
+	}
+
 	return nil
 }
 
+// Close releases the disk store.
+func (c *Context) Close() error {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	err := c.store.Close()
+	c.store = nil
+
+	return err
+}
+
+// swapStore closes the disk store and opens the one for apiHost.
+func (c *Context) swapStore(apiHost string) error {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	if err := c.store.Close(); err != nil {
+		return fmt.Errorf("close disk store: %w", err)
+	}
+
+	store, err := OpenDiskStore(apiHost)
+	if err != nil {
+		slog.Warn("disk cache is unavailable", "apiHost", apiHost, "error", err)
+	}
+
+	c.store = store
+
+	return nil
+}
+
+// diskStore returns the current disk store, which is nil if it is unavailable.
+func (c *Context) diskStore() *DiskStore {
+	c.storeMu.RLock()
+	defer c.storeMu.RUnlock()
+
+	return c.store
+}
+
+// withStore runs fn with store unless the disk store has been swapped out or closed
+// since store was taken from diskStore, and reports whether fn was run.
+func (c *Context) withStore(store *DiskStore, fn func(store *DiskStore)) bool {
+	c.storeMu.RLock()
+	defer c.storeMu.RUnlock()
+
+	if c.store != store {
+		return false
+	}
+
+	fn(store)
+
+	return true
+}
+
+// IsOffline reports whether traQ was unreachable on the last request.
+// The caches keep serving values from disk while offline, but writes are refused.
+func (c *Context) IsOffline() bool {
+	return c.offline.Load()
+}
+
+// CheckConnection probes traQ and leaves offline mode if it is reachable again.
+func (c *Context) CheckConnection(ctx context.Context) error {
+	if _, err := c.client.GetServerVersion(ctx); err != nil {
+		c.setOffline(IsUnreachable(err))
+		return fmt.Errorf("get server version: %w", err)
+	}
+
+	c.setOffline(false)
+
+	return nil
+}
+
+// Revalidations returns a channel that receives keys whose values served from disk
+// have been replaced with fresh ones.
+func (c *Context) Revalidations() <-chan Revalidation {
+	return c.revalidations
+}
+
+func (c *Context) setOffline(offline bool) {
+	if c.offline.Swap(offline) != offline {
+		slog.Info("connection state changed", "offline", offline)
+	}
+}
+
+func (c *Context) notifyRevalidation(r Revalidation) {
+	select {
+	case c.revalidations <- r:
+	default:
+		slog.Warn("revalidation dropped", "bucket", r.Bucket, "key", r.Key)
+	}
+}
+
 func (c *Context) PostMessage(ctx context.Context, request traqapi.PostMessageRequest, channelID uuid.UUID) (traqapi.PostMessageRes, error) {
+	if c.IsOffline() {
+		return nil, ErrOffline
+	}
+
+	return c.postMessage(ctx, request, channelID)
+}
+
+// postMessage posts a message even while offline, so that the outbox can probe traQ.
+func (c *Context) postMessage(ctx context.Context, request traqapi.PostMessageRequest, channelID uuid.UUID) (traqapi.PostMessageRes, error) {
 	res, err := c.client.PostMessage(
 		ctx,
 		traqapi.NewOptPostMessageRequest(request),
@@ -126,25 +369,154 @@ func (c *Context) PostMessage(ctx context.Context, request traqapi.PostMessageRe
 		},
 	)
 	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
 		return nil, fmt.Errorf("post to channel %s: %w", channelID, err)
 	}
 
+	c.setOffline(false)
 	c.Messages.Forget(channelID)
 
 	return res, nil
 }
 
+// EditMessage replaces the content of a message and updates the cached messages of the channel.
+func (c *Context) EditMessage(ctx context.Context, channelID, messageID uuid.UUID, content string) error {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	res, err := c.client.EditMessage(
+		ctx,
+		traqapi.NewOptPostMessageRequest(traqapi.PostMessageRequest{
+			Content: content,
+			Embed:   traqapi.NewOptBool(true),
+		}),
+		traqapi.EditMessageParams{
+			MessageId: messageID,
+		},
+	)
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return fmt.Errorf("edit message %s: %w", messageID, err)
+	}
+
+	switch res.(type) {
+	case *traqapi.EditMessageNoContent:
+	case *traqapi.EditMessageBadRequest:
+		return errors.New("edit message: bad request")
+	case *traqapi.EditMessageForbidden:
+		return errors.New("edit message: forbidden")
+	case *traqapi.EditMessageNotFound:
+		return errors.New("edit message: not found")
+	default:
+		return errors.New("edit message: unreachable error")
+	}
+
+	c.updateCachedMessages(channelID, func(messages []traqapi.Message) []traqapi.Message {
+		for i := range messages {
+			if messages[i].GetID() == messageID {
+				messages[i].SetContent(content)
+				messages[i].SetUpdatedAt(time.Now())
+			}
+		}
+
+		return messages
+	})
+
+	return nil
+}
+
+// DeleteMessage deletes a message and removes it from the cached messages of the channel.
+func (c *Context) DeleteMessage(ctx context.Context, channelID, messageID uuid.UUID) error {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	res, err := c.client.DeleteMessage(ctx, traqapi.DeleteMessageParams{
+		MessageId: messageID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return fmt.Errorf("delete message %s: %w", messageID, err)
+	}
+
+	switch res.(type) {
+	case *traqapi.DeleteMessageNoContent:
+	case *traqapi.DeleteMessageForbidden:
+		return errors.New("delete message: forbidden")
+	case *traqapi.DeleteMessageNotFound:
+		return errors.New("delete message: not found")
+	default:
+		return errors.New("delete message: unreachable error")
+	}
+
+	c.updateCachedMessages(channelID, func(messages []traqapi.Message) []traqapi.Message {
+		return slices.DeleteFunc(messages, func(message traqapi.Message) bool {
+			return message.GetID() == messageID
+		})
+	})
+
+	return nil
+}
+
+// updateCachedMessages applies update to a copy of the cached messages of the channel.
+// The next load of the channel returns the updated messages without fetching them.
+func (c *Context) updateCachedMessages(channelID uuid.UUID, update func([]traqapi.Message) []traqapi.Message) {
+	messages, ok := c.Messages.GetIfExists(channelID)
+	if ok {
+		c.localMessages.Store(channelID, update(slices.Clone(messages)))
+	}
+
+	c.Messages.Forget(channelID)
+}
+
+// newMessageLinkPattern returns a pattern that matches links to messages on apiHost,
+// capturing the message ID.
+func newMessageLinkPattern(apiHost string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`https://` + regexp.QuoteMeta(apiHost) + `/messages/([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`,
+	)
+}
+
+// FindMessageLinks returns the IDs of messages linked from content, without duplicates.
+func (c *Context) FindMessageLinks(content string) []uuid.UUID {
+	ids := make([]uuid.UUID, 0)
+	for _, match := range c.messageLinkPattern.FindAllStringSubmatch(content, -1) {
+		id, err := uuid.Parse(match[1])
+		if err != nil || slices.Contains(ids, id) {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
 func wrapf(errp *error, format string, args ...any) {
 	if *errp != nil {
 		*errp = fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), *errp)
 	}
 }
 
-func newMessagesStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, []traqapi.Message], error) {
+func newMessagesStore(c *Context, traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, []traqapi.Message], error) {
 	freshFor := time.Minute * 5
 	ttl := time.Minute * 10
 
-	return sc.New(func(ctx context.Context, channelID uuid.UUID) (messages []traqapi.Message, err error) {
+	return sc.New(persist(c, BucketMessages, &c.Messages, func(ctx context.Context, channelID uuid.UUID) (messages []traqapi.Message, err error) {
+		if local, ok := c.localMessages.LoadAndDelete(channelID); ok {
+			return local.([]traqapi.Message), nil
+		}
+
 		defer wrapf(&err, "get messages from traQ for channel %s", channelID.String())
 
 		res, err := traqClient.GetMessages(ctx, traqapi.GetMessagesParams{
@@ -164,17 +536,44 @@ func newMessagesStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, []traqap
 		case *traqapi.GetMessagesNotFound:
 			return nil, errors.New("not found")
 
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}), freshFor, ttl)
+}
+
+func newCitedMessagesStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, *traqapi.Message], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, messageID uuid.UUID) (message *traqapi.Message, err error) {
+		defer wrapf(&err, "get message %s from traQ", messageID)
+
+		res, err := traqClient.GetMessage(ctx, traqapi.GetMessageParams{
+			MessageId: messageID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.Message:
+			return res, nil
+
+		case *traqapi.GetMessageNotFound:
+			return nil, errors.New("not found")
+
 		default:
 			return nil, fmt.Errorf("unreachable error")
 		}
 	}, freshFor, ttl)
 }
 
-func newUsersStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.User], error) {
+func newUsersStore(c *Context, traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.User], error) {
 	freshFor := time.Minute * 5
 	ttl := time.Minute * 10
 
-	return sc.New(func(ctx context.Context, _ struct{}) (users []traqapi.User, err error) {
+	return sc.New(persist(c, BucketUsers, &c.Users, func(ctx context.Context, _ struct{}) (users []traqapi.User, err error) {
 		defer wrapf(&err, "get users from traQ")
 
 		res, err := traqClient.GetUsers(ctx, traqapi.GetUsersParams{})
@@ -192,14 +591,30 @@ func newUsersStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.Us
 		default:
 			return nil, fmt.Errorf("unreachable error")
 		}
+	}), freshFor, ttl)
+}
+
+func newUserGroupsStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.UserGroup], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, _ struct{}) (groups []traqapi.UserGroup, err error) {
+		defer wrapf(&err, "get user groups from traQ")
+
+		groups, err = traqClient.GetUserGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return groups, nil
 	}, freshFor, ttl)
 }
 
-func newStampsStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.StampWithThumbnail], error) {
+func newStampsStore(c *Context, traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.StampWithThumbnail], error) {
 	freshFor := time.Minute * 5
 	ttl := time.Minute * 10
 
-	return sc.New(func(ctx context.Context, _ struct{}) (stamps []traqapi.StampWithThumbnail, err error) {
+	return sc.New(persist(c, BucketStamps, &c.Stamps, func(ctx context.Context, _ struct{}) (stamps []traqapi.StampWithThumbnail, err error) {
 		defer wrapf(&err, "get stamps from traQ")
 
 		stamps, err = traqClient.GetStamps(ctx, traqapi.GetStampsParams{
@@ -210,52 +625,23 @@ func newStampsStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, []traqapi.S
 		}
 
 		return stamps, nil
-	}, freshFor, ttl)
+	}), freshFor, ttl)
 }
 
-func newStampImagesStore(traqClient *traqapi.Client) (_ *sc.Cache[uuid.UUID, image.Image], err error) {
-	g := goalie.New()
-	defer g.Collect(&err)
-
+func newStampImagesStore(c *Context, traqClient *traqapi.Client, store *StampImageStore) (*sc.Cache[uuid.UUID, image.Image], error) {
 	freshFor := time.Minute * 5
 	ttl := time.Minute * 10
 
-	return sc.New(func(ctx context.Context, stampID uuid.UUID) (image.Image, error) {
-		baseCacheDir, err := os.UserCacheDir()
-		if err != nil {
-			return nil, fmt.Errorf("get user cache dir: %w", err)
-		}
-
-		cacheDir := path.Join(baseCacheDir, "lazytraq", "stamp_images")
-		if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
-			return nil, fmt.Errorf("create stamp image cache dir: %w", err)
-		}
+	return sc.New(func(ctx context.Context, stampID uuid.UUID) (_ image.Image, err error) {
+		defer wrapf(&err, "get stamp image for stamp %s", stampID)
 
-		cacheRoot, err := os.OpenRoot(cacheDir)
+		fileID, err := c.stampFileID(ctx, stampID)
 		if err != nil {
-			return nil, fmt.Errorf("open stamp image cache dir: %w", err)
+			return nil, fmt.Errorf("get stamp file id: %w", err)
 		}
-		defer g.Guard(cacheRoot.Close)
 
-		stampCacheDir := path.Join(cacheDir, stampID.String())
-		entries, err := os.ReadDir(stampCacheDir)
-		if err != nil && !os.IsNotExist(err) {
-			return nil, fmt.Errorf("read stamp image cache subdir (%s): %w", stampCacheDir, err)
-		}
-
-		if len(entries) > 0 {
-			filename := entries[0].Name()
-			f, err := os.Open(path.Join(stampCacheDir, filename))
-			if err == nil {
-				defer g.Guard(f.Close)
-
-				img, _, err := image.Decode(f)
-				if err != nil {
-					return nil, fmt.Errorf("decode cached stamp image (%s): %w", filename, err)
-				}
-
-				return img, nil
-			}
+		if img, ok := store.Load(stampID, fileID); ok {
+			return img, nil
 		}
 
 		res, err := traqClient.GetStampImage(ctx, traqapi.GetStampImageParams{
@@ -269,6 +655,7 @@ func newStampImagesStore(traqClient *traqapi.Client) (_ *sc.Cache[uuid.UUID, ima
 		var ext string
 		switch res := res.(type) {
 		case *traqapi.GetStampImageNotFound:
+			invalidateStampImage(ctx, store, stampID)
 			return nil, fmt.Errorf("stamp image not found")
 		case *traqapi.GetStampImageOKImageGIF:
 			r = res
@@ -293,34 +680,60 @@ func newStampImagesStore(traqClient *traqapi.Client) (_ *sc.Cache[uuid.UUID, ima
 
 		img, _, err := image.Decode(bytes.NewReader(b))
 		if err != nil {
+			// an image of an earlier file ID would otherwise be kept for the stamp
+			invalidateStampImage(ctx, store, stampID)
 			return nil, fmt.Errorf("decode stamp image: %w", err)
 		}
 
-		if err := cacheRoot.MkdirAll(stampID.String(), os.ModePerm); err != nil {
-			return nil, fmt.Errorf("create stamp image cache subdir (%s): %w", stampID.String(), err)
+		if err := store.Save(stampID, fileID, ext, b); err != nil {
+			slog.WarnContext(ctx, "save stamp image to cache", "stampID", stampID, "error", err)
 		}
 
-		filename := fmt.Sprintf("%s/%s.%s", stampID, stampID, ext)
-		f, err := cacheRoot.Create(filename)
-		if err != nil {
-			return nil, fmt.Errorf("create temp file (%s) for stamp image: %w", filename, err)
-		}
-		defer g.Guard(f.Close)
+		return img, nil
+	}, freshFor, ttl)
+}
 
-		_, err = io.Copy(f, bytes.NewReader(b))
-		if err != nil {
-			return nil, fmt.Errorf("copy stamp image data to temp file (%s): %w", filename, err)
+func invalidateStampImage(ctx context.Context, store *StampImageStore, stampID uuid.UUID) {
+	if err := store.Invalidate(stampID); err != nil {
+		slog.WarnContext(ctx, "invalidate stamp image cache", "stampID", stampID, "error", err)
+	}
+}
+
+// stampFileID returns the file ID of the current image of the stamp.
+func (c *Context) stampFileID(ctx context.Context, stampID uuid.UUID) (uuid.UUID, error) {
+	stamps, err := c.Stamps.Get(ctx, struct{}{})
+	if err == nil {
+		for _, stamp := range stamps {
+			if stamp.GetID() == stampID {
+				return stamp.GetFileId(), nil
+			}
 		}
+	}
 
-		return img, nil
-	}, freshFor, ttl)
+	res, err := c.client.GetStamp(ctx, traqapi.GetStampParams{
+		StampId: stampID,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("get stamp from traQ: %w", err)
+	}
+
+	switch res := res.(type) {
+	case *traqapi.Stamp:
+		return res.GetFileId(), nil
+
+	case *traqapi.GetStampNotFound:
+		return uuid.Nil, errors.New("stamp not found")
+
+	default:
+		return uuid.Nil, fmt.Errorf("unreachable error")
+	}
 }
 
-func newChannelsStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, *traqapi.ChannelList], error) {
+func newChannelsStore(c *Context, traqClient *traqapi.Client) (*sc.Cache[struct{}, *traqapi.ChannelList], error) {
 	freshFor := time.Minute * 5
 	ttl := time.Minute * 10
 
-	return sc.New(func(ctx context.Context, _ struct{}) (channels *traqapi.ChannelList, err error) {
+	return sc.New(persist(c, BucketChannels, &c.Channels, func(ctx context.Context, _ struct{}) (channels *traqapi.ChannelList, err error) {
 		defer wrapf(&err, "get channels from traQ")
 
 		channels, err = traqClient.GetChannels(ctx, traqapi.GetChannelsParams{})
@@ -329,7 +742,7 @@ func newChannelsStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, *traqapi.
 		}
 
 		return channels, nil
-	}, freshFor, ttl)
+	}), freshFor, ttl)
 }
 
 func newMeStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, *traqapi.MyUserDetail], error) {
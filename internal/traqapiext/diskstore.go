@@ -0,0 +1,238 @@
+package traqapiext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/motoki317/sc"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	BucketMessages = "messages"
+	BucketUsers    = "users"
+	BucketChannels = "channels"
+	BucketStamps   = "stamps"
+)
+
+// ErrOffline is returned by write operations while traQ is unreachable.
+var ErrOffline = errors.New("traQ is unreachable (offline mode)")
+
+// Revalidation reports that a value served from disk has been replaced by a fresh one.
+type Revalidation struct {
+	Bucket string
+	Key    string
+}
+
+// DiskStore persists cache entries in a bbolt database so that they survive restarts
+// and can be served while traQ is unreachable.
+type DiskStore struct {
+	db *bbolt.DB
+}
+
+type diskEntry struct {
+	SavedAt time.Time       `json:"savedAt"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// CacheDir returns the directory where lazytraq keeps its caches.
+func CacheDir() (string, error) {
+	baseCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("get user cache dir: %w", err)
+	}
+
+	return path.Join(baseCacheDir, "lazytraq"), nil
+}
+
+// DiskStorePath returns the path of the disk store database for apiHost.
+func DiskStorePath(apiHost string) (string, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(cacheDir, apiHost+".db"), nil
+}
+
+func OpenDiskStore(apiHost string) (*DiskStore, error) {
+	dbPath, err := DiskStorePath(apiHost)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(path.Dir(dbPath), 0o700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	db, err := bbolt.Open(dbPath, 0o600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open cache db (%s): %w", dbPath, err)
+	}
+
+	return &DiskStore{db: db}, nil
+}
+
+func (s *DiskStore) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
+
+// Load decodes the entry for key into v. ok is false if there is no entry.
+func (s *DiskStore) Load(bucket, key string, v any) (savedAt time.Time, ok bool, err error) {
+	if s == nil {
+		return time.Time{}, false, nil
+	}
+
+	var entry diskEntry
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		data := b.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		ok = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil || !ok {
+		return time.Time{}, false, err
+	}
+
+	if err := json.Unmarshal(entry.Value, v); err != nil {
+		return time.Time{}, false, fmt.Errorf("decode %s/%s: %w", bucket, key, err)
+	}
+
+	return entry.SavedAt, true, nil
+}
+
+func (s *DiskStore) Save(bucket, key string, v any) error {
+	if s == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s/%s: %w", bucket, key, err)
+	}
+
+	data, err := json.Marshal(diskEntry{
+		SavedAt: time.Now(),
+		Value:   value,
+	})
+	if err != nil {
+		return fmt.Errorf("encode %s/%s: %w", bucket, key, err)
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return fmt.Errorf("create bucket %s: %w", bucket, err)
+		}
+
+		return b.Put([]byte(key), data)
+	})
+}
+
+// IsUnreachable reports whether err is a network error, i.e. traQ could not be reached.
+func IsUnreachable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// persist backs replaceFn with the disk store.
+//
+// The first load of a key in a session is served from disk, if present, and revalidated
+// in the background (stale-while-revalidate); the fresh value is picked up by the next
+// load after the key is forgotten and a Revalidation is sent. When traQ is unreachable
+// the value on disk is served instead and the context enters offline mode.
+func persist[K comparable, V any](c *Context, bucket string, cache **sc.Cache[K, V], replaceFn func(ctx context.Context, key K) (V, error)) func(ctx context.Context, key K) (V, error) {
+	var (
+		warmed      sync.Map // map[K]struct{}
+		revalidated sync.Map // map[K]V
+	)
+
+	return func(ctx context.Context, key K) (V, error) {
+		diskKey := fmt.Sprint(key)
+		store := c.diskStore()
+
+		if v, ok := revalidated.LoadAndDelete(key); ok {
+			return v.(V), nil
+		}
+
+		if _, loaded := warmed.LoadOrStore(key, struct{}{}); !loaded {
+			var v V
+			_, ok, err := store.Load(bucket, diskKey, &v)
+			if err != nil {
+				slog.WarnContext(ctx, "load from disk cache", "bucket", bucket, "key", diskKey, "error", err)
+			}
+
+			if ok {
+				go func() {
+					fresh, err := replaceFn(context.WithoutCancel(ctx), key)
+					if err != nil {
+						c.setOffline(IsUnreachable(err))
+						return
+					}
+
+					c.setOffline(false)
+					// the store and the caches belong to another host after SwitchHost
+					current := c.withStore(store, func(store *DiskStore) {
+						if err := store.Save(bucket, diskKey, fresh); err != nil {
+							slog.Warn("save to disk cache", "bucket", bucket, "key", diskKey, "error", err)
+						}
+					})
+					if !current {
+						return
+					}
+
+					revalidated.Store(key, fresh)
+					(*cache).Forget(key)
+					c.notifyRevalidation(Revalidation{Bucket: bucket, Key: diskKey})
+				}()
+
+				return v, nil
+			}
+		}
+
+		v, err := replaceFn(ctx, key)
+		if err != nil {
+			if !IsUnreachable(err) {
+				return v, err
+			}
+
+			c.setOffline(true)
+
+			var stale V
+			if _, ok, loadErr := store.Load(bucket, diskKey, &stale); loadErr == nil && ok {
+				return stale, nil
+			}
+
+			return v, err
+		}
+
+		c.setOffline(false)
+		c.withStore(store, func(store *DiskStore) {
+			if err := store.Save(bucket, diskKey, v); err != nil {
+				slog.WarnContext(ctx, "save to disk cache", "bucket", bucket, "key", diskKey, "error", err)
+			}
+		})
+
+		return v, nil
+	}
+}
@@ -0,0 +1,147 @@
+package traqapiext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Draft is an unsent message in a channel.
+type Draft struct {
+	Content string `json:"content"`
+	// Embeds maps completed mentions and channel links in Content to their embeds.
+	Embeds    map[string]string `json:"embeds,omitempty"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// DraftStore keeps drafts per channel in <UserConfigDir>/lazytraq/drafts/<apiHost>.json,
+// so that they survive crashes and restarts. Unlike the caches, drafts are not removed
+// by `lazytraq cache clean`.
+type DraftStore struct {
+	// path is empty if the drafts are kept in memory only.
+	path string
+
+	mu     sync.Mutex
+	drafts map[uuid.UUID]Draft
+}
+
+// OpenDraftStore loads the drafts for apiHost.
+func OpenDraftStore(apiHost string) (*DraftStore, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("get user config dir: %w", err)
+	}
+
+	draftPath := filepath.Join(configDir, "lazytraq", "drafts", apiHost+".json")
+	s := &DraftStore{
+		path:   draftPath,
+		drafts: make(map[uuid.UUID]Draft),
+	}
+
+	data, err := os.ReadFile(draftPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+
+		return nil, fmt.Errorf("read drafts (%s): %w", draftPath, err)
+	}
+
+	if err := json.Unmarshal(data, &s.drafts); err != nil {
+		return nil, fmt.Errorf("decode drafts (%s): %w", draftPath, err)
+	}
+
+	return s, nil
+}
+
+// newMemoryDraftStore returns a store that keeps drafts only until the process exits.
+func newMemoryDraftStore() *DraftStore {
+	return &DraftStore{
+		drafts: make(map[uuid.UUID]Draft),
+	}
+}
+
+// Get returns the draft for the channel.
+func (s *DraftStore) Get(channelID uuid.UUID) (Draft, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	draft, ok := s.drafts[channelID]
+	return draft, ok
+}
+
+// Set stores the draft for the channel and writes all drafts to disk.
+// A draft with blank content is removed.
+func (s *DraftStore) Set(channelID uuid.UUID, draft Draft) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.drafts[channelID]
+	if strings.TrimSpace(draft.Content) == "" {
+		if !exists {
+			return nil
+		}
+
+		delete(s.drafts, channelID)
+	} else {
+		// completing a word already in the draft changes only its embeds
+		if exists && old.Content == draft.Content && maps.Equal(old.Embeds, draft.Embeds) {
+			return nil
+		}
+
+		draft.UpdatedAt = time.Now()
+		s.drafts[channelID] = draft
+	}
+
+	return s.saveLocked()
+}
+
+// ChannelIDs returns the channels that have drafts.
+func (s *DraftStore) ChannelIDs() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]uuid.UUID, 0, len(s.drafts))
+	for id := range s.drafts {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// saveLocked writes the drafts to a temporary file and renames it, so that a crash
+// while writing does not lose the previous drafts.
+func (s *DraftStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.drafts)
+	if err != nil {
+		return fmt.Errorf("encode drafts: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("create drafts dir: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("write drafts (%s): %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("rename drafts (%s): %w", s.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package traqapiext
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+// lookupIndex memoizes a map built from a cached value, and rebuilds it
+// when the cache returns a different value.
+type lookupIndex[S any, K comparable, V any] struct {
+	mu     sync.Mutex
+	source *S
+	index  map[K]V
+}
+
+func (l *lookupIndex[S, K, V]) get(source *S, build func() map[K]V) map[K]V {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.source != source || l.index == nil {
+		l.source = source
+		l.index = build()
+	}
+
+	return l.index
+}
+
+// UserByID looks up a user in the Users cache without fetching it.
+func (c *Context) UserByID(id uuid.UUID) (traqapi.User, bool) {
+	users, ok := c.Users.GetIfExists(struct{}{})
+	if !ok || len(users) == 0 {
+		return traqapi.User{}, false
+	}
+
+	index := c.usersByID.get(&users[0], func() map[uuid.UUID]traqapi.User {
+		index := make(map[uuid.UUID]traqapi.User, len(users))
+		for _, user := range users {
+			index[user.GetID()] = user
+		}
+
+		return index
+	})
+
+	user, ok := index[id]
+	return user, ok
+}
+
+// UserByName looks up a user by name in the Users cache without fetching it.
+func (c *Context) UserByName(name string) (traqapi.User, bool) {
+	users, ok := c.Users.GetIfExists(struct{}{})
+	if !ok || len(users) == 0 {
+		return traqapi.User{}, false
+	}
+
+	index := c.usersByName.get(&users[0], func() map[string]traqapi.User {
+		index := make(map[string]traqapi.User, len(users))
+		for _, user := range users {
+			index[user.GetName()] = user
+		}
+
+		return index
+	})
+
+	user, ok := index[name]
+	return user, ok
+}
+
+// StampByName looks up a stamp in the Stamps cache without fetching it.
+func (c *Context) StampByName(name string) (traqapi.StampWithThumbnail, bool) {
+	stamps, ok := c.Stamps.GetIfExists(struct{}{})
+	if !ok || len(stamps) == 0 {
+		return traqapi.StampWithThumbnail{}, false
+	}
+
+	index := c.stampsByName.get(&stamps[0], func() map[string]traqapi.StampWithThumbnail {
+		index := make(map[string]traqapi.StampWithThumbnail, len(stamps))
+		for _, stamp := range stamps {
+			index[stamp.GetName()] = stamp
+		}
+
+		return index
+	})
+
+	stamp, ok := index[name]
+	return stamp, ok
+}
+
+// StampByID looks up a stamp in the Stamps cache without fetching it.
+func (c *Context) StampByID(id uuid.UUID) (traqapi.StampWithThumbnail, bool) {
+	stamps, ok := c.Stamps.GetIfExists(struct{}{})
+	if !ok || len(stamps) == 0 {
+		return traqapi.StampWithThumbnail{}, false
+	}
+
+	index := c.stampsByID.get(&stamps[0], func() map[uuid.UUID]traqapi.StampWithThumbnail {
+		index := make(map[uuid.UUID]traqapi.StampWithThumbnail, len(stamps))
+		for _, stamp := range stamps {
+			index[stamp.GetID()] = stamp
+		}
+
+		return index
+	})
+
+	stamp, ok := index[id]
+	return stamp, ok
+}
+
+// ChannelPath returns the full path of a public channel, e.g. "gps/times/ras",
+// looked up in the Channels cache without fetching it.
+func (c *Context) ChannelPath(id uuid.UUID) (string, bool) {
+	channels, ok := c.Channels.GetIfExists(struct{}{})
+	if !ok || channels == nil {
+		return "", false
+	}
+
+	index := c.channelPaths.get(channels, func() map[uuid.UUID]string {
+		return buildChannelPaths(channels.Public)
+	})
+
+	path, ok := index[id]
+	return path, ok
+}
+
+// ChannelByID looks up a public channel in the Channels cache without fetching it.
+func (c *Context) ChannelByID(id uuid.UUID) (traqapi.Channel, bool) {
+	channels, ok := c.Channels.GetIfExists(struct{}{})
+	if !ok || channels == nil {
+		return traqapi.Channel{}, false
+	}
+
+	index := c.channelsByID.get(channels, func() map[uuid.UUID]traqapi.Channel {
+		index := make(map[uuid.UUID]traqapi.Channel, len(channels.Public))
+		for _, channel := range channels.Public {
+			index[channel.GetID()] = channel
+		}
+
+		return index
+	})
+
+	channel, ok := index[id]
+	return channel, ok
+}
+
+// ChannelByPath returns the ID of the public channel at path, e.g. "gps/times/ras",
+// looked up in the Channels cache without fetching it.
+func (c *Context) ChannelByPath(path string) (uuid.UUID, bool) {
+	channels, ok := c.Channels.GetIfExists(struct{}{})
+	if !ok || channels == nil {
+		return uuid.Nil, false
+	}
+
+	index := c.channelsByPath.get(channels, func() map[string]uuid.UUID {
+		index := make(map[string]uuid.UUID, len(channels.Public))
+		for id, path := range buildChannelPaths(channels.Public) {
+			index[path] = id
+		}
+
+		return index
+	})
+
+	id, ok := index[path]
+	return id, ok
+}
+
+func buildChannelPaths(channels []traqapi.Channel) map[uuid.UUID]string {
+	byID := make(map[uuid.UUID]traqapi.Channel, len(channels))
+	for _, channel := range channels {
+		byID[channel.GetID()] = channel
+	}
+
+	paths := make(map[uuid.UUID]string, len(channels))
+	for _, channel := range channels {
+		names := []string{channel.GetName()}
+		parentID, ok := channel.GetParentId().Get()
+		for ok {
+			parent, exists := byID[parentID]
+			if !exists {
+				break
+			}
+
+			names = append(names, parent.GetName())
+			parentID, ok = parent.GetParentId().Get()
+		}
+
+		slices.Reverse(names)
+
+		paths[channel.GetID()] = strings.Join(names, "/")
+	}
+
+	return paths
+}
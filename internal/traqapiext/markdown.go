@@ -0,0 +1,282 @@
+package traqapiext
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+)
+
+// TokenKind is the kind of a traQ-specific markdown element.
+type TokenKind int
+
+const (
+	TokenUser TokenKind = iota + 1
+	TokenGroup
+	TokenChannel
+	TokenStamp
+	TokenSpoiler
+)
+
+// MarkdownToken is a traQ-specific element that has been replaced with plain text.
+// Text is how the element appears in Markdown.Content, so that renderers can style it.
+type MarkdownToken struct {
+	Kind TokenKind
+	Text string
+	ID   uuid.UUID
+}
+
+// Markdown is message content with the traQ extensions resolved to CommonMark.
+type Markdown struct {
+	Content string
+	Tokens  []MarkdownToken
+}
+
+// MarkdownOptions controls how traQ-specific elements are converted.
+type MarkdownOptions struct {
+	// RevealSpoilers shows the text of !!spoilers!! instead of masking it.
+	RevealSpoilers bool
+	// MarkTokens wraps the text of tokens in Content with invisible markers, so that
+	// renderers can find them in their output. See TokenMarkerKind.
+	MarkTokens bool
+}
+
+// TokenEnd ends the text of a token marked by MarkTokens. The text starts with a
+// marker that depends on the kind of the token. The markers are zero-width format
+// characters, which markdown renderers keep as they are.
+const TokenEnd = '\u206f'
+
+func tokenStart(kind TokenKind) rune {
+	return '\u2069' + rune(kind)
+}
+
+// TokenMarkerKind returns the kind of token that r starts, if r is a start marker.
+func TokenMarkerKind(r rune) (TokenKind, bool) {
+	kind := TokenKind(r - '\u2069')
+	if kind < TokenUser || kind > TokenSpoiler {
+		return 0, false
+	}
+
+	return kind, true
+}
+
+// isTokenMarker reports whether r is a start marker or TokenEnd.
+func isTokenMarker(r rune) bool {
+	_, ok := TokenMarkerKind(r)
+	return ok || r == TokenEnd
+}
+
+// embed is the JSON embedded in message content as !{...}.
+type embed struct {
+	Type string    `json:"type"`
+	Raw  string    `json:"raw"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// FormatEmbed returns the !{...} form of a mention or channel link that traQ
+// turns into a notification or a link. typ is "user", "group" or "channel".
+func FormatEmbed(typ, raw string, id uuid.UUID) string {
+	b, err := json.Marshal(embed{
+		Type: typ,
+		Raw:  raw,
+		ID:   id,
+	})
+	if err != nil {
+		return raw
+	}
+
+	return "!" + string(b)
+}
+
+// CollapseEmbeds replaces the embeds in content with their raw text, e.g. to edit the
+// content of a message. It returns the embeds keyed by their raw text.
+func CollapseEmbeds(content string) (string, map[string]string) {
+	embeds := make(map[string]string)
+	collapsed := embedPattern.ReplaceAllStringFunc(content, func(s string) string {
+		var e embed
+		if err := json.Unmarshal([]byte(s[1:]), &e); err != nil || e.Raw == "" {
+			return s
+		}
+
+		embeds[e.Raw] = s
+		return e.Raw
+	})
+
+	return collapsed, embeds
+}
+
+var (
+	embedPattern      = regexp.MustCompile(`!\{(?:[^{}"]|"(?:[^"\\]|\\.)*")*\}`)
+	stampPattern      = regexp.MustCompile(`:([a-zA-Z0-9_\-]{1,32})(?:\.[a-zA-Z0-9_\-.]+)?:`)
+	spoilerPattern    = regexp.MustCompile(`!!(.+?)!!`)
+	blockMathPattern  = regexp.MustCompile(`(?s)\$\$(.+?)\$\$`)
+	inlineCodePattern = regexp.MustCompile("`[^`\n]+`")
+
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		"*", `\*`,
+		"_", `\_`,
+		"`", "\\`",
+		"[", `\[`,
+		"]", `\]`,
+		"~", `\~`,
+	)
+)
+
+// ParseMarkdown converts traQ-flavoured markdown (embedded mentions and channel links,
+// :stamps:, !!spoilers!! and $$math$$) to CommonMark. Users, channels and stamps are
+// resolved through the caches without fetching them; unresolved embeds fall back to
+// their raw text.
+func (c *Context) ParseMarkdown(content string, opts MarkdownOptions) Markdown {
+	p := markdownParser{
+		c:    c,
+		opts: opts,
+	}
+
+	if opts.MarkTokens {
+		// so that markers in messages are not confused with the ones added
+		content = strings.Map(func(r rune) rune {
+			if isTokenMarker(r) {
+				return -1
+			}
+
+			return r
+		}, content)
+	}
+
+	var b strings.Builder
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			b.WriteString(line)
+			continue
+		}
+
+		if inFence {
+			b.WriteString(line)
+			continue
+		}
+
+		b.WriteString(p.parseLine(line))
+	}
+
+	return Markdown{
+		Content: blockMathPattern.ReplaceAllStringFunc(b.String(), func(s string) string {
+			math := strings.TrimSpace(blockMathPattern.FindStringSubmatch(s)[1])
+			return "`" + strings.ReplaceAll(math, "\n", " ") + "`"
+		}),
+		Tokens: p.tokens,
+	}
+}
+
+type markdownParser struct {
+	c      *Context
+	opts   MarkdownOptions
+	tokens []MarkdownToken
+}
+
+// parseLine converts a line outside fenced code blocks, leaving inline code as is.
+func (p *markdownParser) parseLine(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range inlineCodePattern.FindAllStringIndex(line, -1) {
+		b.WriteString(p.parseText(line[last:loc[0]]))
+		b.WriteString(line[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(p.parseText(line[last:]))
+
+	return b.String()
+}
+
+func (p *markdownParser) parseText(text string) string {
+	text = embedPattern.ReplaceAllStringFunc(text, p.replaceEmbed)
+	text = spoilerPattern.ReplaceAllStringFunc(text, p.replaceSpoiler)
+	text = stampPattern.ReplaceAllStringFunc(text, p.replaceStamp)
+
+	return text
+}
+
+func (p *markdownParser) replaceEmbed(s string) string {
+	var e embed
+	if err := json.Unmarshal([]byte(s[1:]), &e); err != nil {
+		return s
+	}
+
+	text := e.Raw
+	var kind TokenKind
+	switch e.Type {
+	case "user":
+		kind = TokenUser
+		if user, ok := p.c.UserByID(e.ID); ok {
+			text = "@" + user.GetName()
+		}
+
+	case "group":
+		kind = TokenGroup
+
+	case "channel":
+		kind = TokenChannel
+		if path, ok := p.c.ChannelPath(e.ID); ok {
+			text = "#" + path
+		}
+
+	default:
+		return markdownEscaper.Replace(text)
+	}
+
+	p.tokens = append(p.tokens, MarkdownToken{
+		Kind: kind,
+		Text: text,
+		ID:   e.ID,
+	})
+
+	return p.mark(kind, markdownEscaper.Replace(text))
+}
+
+func (p *markdownParser) replaceSpoiler(s string) string {
+	text := spoilerPattern.FindStringSubmatch(s)[1]
+	if !p.opts.RevealSpoilers {
+		text = strings.Repeat("▒", ansi.StringWidth(text))
+	}
+
+	p.tokens = append(p.tokens, MarkdownToken{
+		Kind: TokenSpoiler,
+		Text: text,
+	})
+
+	return p.mark(TokenSpoiler, markdownEscaper.Replace(text))
+}
+
+func (p *markdownParser) replaceStamp(s string) string {
+	name := stampPattern.FindStringSubmatch(s)[1]
+	stamp, ok := p.c.StampByName(name)
+	if !ok {
+		return s
+	}
+
+	text := ":" + name + ":"
+	p.tokens = append(p.tokens, MarkdownToken{
+		Kind: TokenStamp,
+		Text: text,
+		ID:   stamp.GetID(),
+	})
+
+	return p.mark(TokenStamp, markdownEscaper.Replace(text))
+}
+
+// mark wraps the text of a token with its markers if MarkTokens is set.
+func (p *markdownParser) mark(kind TokenKind, text string) string {
+	if !p.opts.MarkTokens {
+		return text
+	}
+
+	return string(tokenStart(kind)) + text + string(TokenEnd)
+}
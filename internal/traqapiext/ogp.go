@@ -0,0 +1,98 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+// maxOgpImageSize bounds the size of an OGP thumbnail downloaded from other sites.
+const maxOgpImageSize = 5 << 20
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// FindPreviewURLs returns the external URLs in content that can have link previews,
+// without duplicates. Links to the traQ host itself are excluded.
+func (c *Context) FindPreviewURLs(content string) []string {
+	urls := make([]string, 0)
+	for _, rawURL := range urlPattern.FindAllString(content, -1) {
+		rawURL = strings.TrimRight(rawURL, ".,!?:;")
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" || u.Host == c.apiHost || slices.Contains(urls, rawURL) {
+			continue
+		}
+
+		urls = append(urls, rawURL)
+	}
+
+	return urls
+}
+
+func newOgpsStore(traqClient *traqapi.Client) (*sc.Cache[string, *traqapi.Ogp], error) {
+	freshFor := time.Hour
+	ttl := time.Hour * 2
+
+	return sc.New(func(ctx context.Context, rawURL string) (ogp *traqapi.Ogp, err error) {
+		defer wrapf(&err, "get ogp of %s from traQ", rawURL)
+
+		res, err := traqClient.GetOgp(ctx, traqapi.GetOgpParams{
+			URL: rawURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.Ogp:
+			return res, nil
+
+		case *traqapi.GetOgpBadRequest:
+			return nil, errors.New("bad request")
+
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
+
+func newOgpImagesStore(httpClient *http.Client) (*sc.Cache[string, image.Image], error) {
+	freshFor := time.Hour
+	ttl := time.Hour * 2
+
+	return sc.New(func(ctx context.Context, imageURL string) (img image.Image, err error) {
+		defer wrapf(&err, "get ogp image %s", imageURL)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status: %s", res.Status)
+		}
+
+		img, _, err = image.Decode(io.LimitReader(res.Body, maxOgpImageSize))
+		if err != nil {
+			return nil, fmt.Errorf("decode image: %w", err)
+		}
+
+		return img, nil
+	}, freshFor, ttl)
+}
@@ -0,0 +1,350 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+const (
+	// maxSendAttempts is the number of times a message is posted before it is marked as failed.
+	maxSendAttempts = 10
+	minSendBackoff  = time.Second
+	maxSendBackoff  = time.Minute
+	// postedLookupLimit is the number of latest messages in which a message that may
+	// have been posted is looked for.
+	postedLookupLimit = 50
+)
+
+// OutboxStatus is the state of a queued message.
+type OutboxStatus int
+
+const (
+	// OutboxSending means the message is being posted.
+	OutboxSending OutboxStatus = iota + 1
+	// OutboxWaiting means traQ was unreachable and the message will be posted again.
+	OutboxWaiting
+	// OutboxSent means traQ accepted the message, but it has not been seen in the channel yet.
+	OutboxSent
+	// OutboxFailed means the message was given up on. It can be retried or edited.
+	OutboxFailed
+)
+
+// OutboxMessage is a message queued for sending.
+type OutboxMessage struct {
+	// Nonce identifies the message in the queue and is sent to traQ with it.
+	Nonce     uuid.UUID
+	ChannelID uuid.UUID
+	Content   string
+	Status    OutboxStatus
+	Attempts  int
+	// MessageID is set once traQ accepted the message.
+	MessageID uuid.UUID
+	// Err is the error of the last attempt.
+	Err      error
+	QueuedAt time.Time
+	// UpdatedAt changes whenever the status changes.
+	UpdatedAt time.Time
+	// maybePosted is set after an attempt that may have been accepted by traQ even
+	// though it failed, e.g. by timing out.
+	maybePosted bool
+}
+
+// Outbox posts messages in the background. Messages stay in the queue until they are
+// seen among the messages fetched from the channel, so that they can be shown
+// optimistically without appearing twice.
+type Outbox struct {
+	c *Context
+	// updates signals that there are channels in updated. It is buffered by one, so
+	// that changes made before the signal is received are coalesced, not dropped.
+	updates chan struct{}
+
+	mu       sync.Mutex
+	messages map[uuid.UUID]*OutboxMessage // keyed by nonce
+	updated  map[uuid.UUID]struct{}       // channel IDs
+}
+
+func newOutbox(c *Context) *Outbox {
+	return &Outbox{
+		c:        c,
+		updates:  make(chan struct{}, 1),
+		messages: make(map[uuid.UUID]*OutboxMessage),
+		updated:  make(map[uuid.UUID]struct{}),
+	}
+}
+
+// Updates returns a channel that receives a signal when the queued messages of some
+// channels changed. TakeUpdated returns those channels.
+func (o *Outbox) Updates() <-chan struct{} {
+	return o.updates
+}
+
+// TakeUpdated returns the IDs of the channels whose queued messages changed since
+// the last call.
+func (o *Outbox) TakeUpdated() []uuid.UUID {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	channelIDs := slices.Collect(maps.Keys(o.updated))
+	clear(o.updated)
+
+	return channelIDs
+}
+
+// Enqueue queues content to be posted to the channel and returns its nonce.
+func (o *Outbox) Enqueue(channelID uuid.UUID, content string) uuid.UUID {
+	now := time.Now()
+	message := &OutboxMessage{
+		Nonce:     uuid.New(),
+		ChannelID: channelID,
+		Content:   content,
+		Status:    OutboxSending,
+		QueuedAt:  now,
+		UpdatedAt: now,
+	}
+
+	o.mu.Lock()
+	o.messages[message.Nonce] = message
+	o.mu.Unlock()
+
+	o.notify(channelID)
+	go o.send(message.Nonce)
+
+	return message.Nonce
+}
+
+// Messages returns the queued messages of the channel in the order they were queued.
+func (o *Outbox) Messages(channelID uuid.UUID) []OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := make([]OutboxMessage, 0)
+	for _, message := range o.messages {
+		if message.ChannelID == channelID {
+			messages = append(messages, *message)
+		}
+	}
+
+	slices.SortFunc(messages, func(a, b OutboxMessage) int {
+		return a.QueuedAt.Compare(b.QueuedAt)
+	})
+
+	return messages
+}
+
+// Retry posts a failed message again.
+func (o *Outbox) Retry(nonce uuid.UUID) bool {
+	o.mu.Lock()
+	message, ok := o.messages[nonce]
+	if !ok || message.Status != OutboxFailed {
+		o.mu.Unlock()
+		return false
+	}
+
+	message.Status = OutboxSending
+	message.Attempts = 0
+	message.Err = nil
+	message.UpdatedAt = time.Now()
+	channelID := message.ChannelID
+	o.mu.Unlock()
+
+	o.notify(channelID)
+	go o.send(nonce)
+
+	return true
+}
+
+// Remove takes a failed message out of the queue, e.g. to edit it.
+func (o *Outbox) Remove(nonce uuid.UUID) (OutboxMessage, bool) {
+	o.mu.Lock()
+	message, ok := o.messages[nonce]
+	if !ok || message.Status != OutboxFailed {
+		o.mu.Unlock()
+		return OutboxMessage{}, false
+	}
+
+	delete(o.messages, nonce)
+	o.mu.Unlock()
+
+	o.notify(message.ChannelID)
+
+	return *message, true
+}
+
+// Acknowledge removes the queued messages that appear in messages fetched from traQ,
+// matched by nonce or by the ID traQ assigned to them.
+func (o *Outbox) Acknowledge(messages []traqapi.Message) {
+	seen := make(map[uuid.UUID]struct{}, len(messages))
+	for _, message := range messages {
+		seen[message.GetID()] = struct{}{}
+		if nonce, ok := message.GetNonce().Get(); ok {
+			if id, err := uuid.Parse(nonce); err == nil {
+				seen[id] = struct{}{}
+			}
+		}
+	}
+
+	changed := make(map[uuid.UUID]struct{})
+	o.mu.Lock()
+	for nonce, message := range o.messages {
+		_, nonceSeen := seen[nonce]
+		_, idSeen := seen[message.MessageID]
+		if nonceSeen || (message.Status == OutboxSent && idSeen) {
+			delete(o.messages, nonce)
+			changed[message.ChannelID] = struct{}{}
+		}
+	}
+	o.mu.Unlock()
+
+	for channelID := range changed {
+		o.notify(channelID)
+	}
+}
+
+// send posts the message, retrying with exponential backoff while traQ is unreachable.
+// An attempt that failed after the request was sent, e.g. by timing out, may have been
+// accepted by traQ, so the message is looked for in the channel before it is posted again.
+func (o *Outbox) send(nonce uuid.UUID) {
+	ctx := context.Background()
+	backoff := minSendBackoff
+	for {
+		o.mu.Lock()
+		message, ok := o.messages[nonce]
+		if !ok {
+			// acknowledged while waiting
+			o.mu.Unlock()
+			return
+		}
+
+		message.Status = OutboxSending
+		message.Attempts++
+		message.UpdatedAt = time.Now()
+		request := traqapi.PostMessageRequest{
+			Content: message.Content,
+			Embed:   traqapi.NewOptBool(true),
+			Nonce:   traqapi.NewOptString(nonce.String()),
+		}
+		channelID := message.ChannelID
+		attempts := message.Attempts
+		maybePosted := message.maybePosted
+		o.mu.Unlock()
+
+		o.notify(channelID)
+
+		var (
+			messageID uuid.UUID
+			posted    bool
+			err       error
+		)
+		if maybePosted {
+			messageID, posted, err = o.findPosted(ctx, channelID, nonce)
+		}
+		if err == nil && !posted {
+			messageID, err = o.post(ctx, request, channelID)
+			maybePosted = IsUnreachable(err) && !isDialError(err)
+		}
+
+		retry := IsUnreachable(err) && attempts < maxSendAttempts
+		if err != nil {
+			slog.WarnContext(ctx, "send message", "nonce", nonce, "attempts", attempts, "retry", retry, "error", err)
+		}
+
+		o.mu.Lock()
+		message, ok = o.messages[nonce]
+		if ok {
+			message.Err = err
+			message.UpdatedAt = time.Now()
+			message.maybePosted = maybePosted
+			switch {
+			case err == nil:
+				message.Status = OutboxSent
+				message.MessageID = messageID
+			case retry:
+				message.Status = OutboxWaiting
+			default:
+				message.Status = OutboxFailed
+			}
+		}
+		o.mu.Unlock()
+
+		o.notify(channelID)
+
+		if !ok || !retry {
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxSendBackoff)
+	}
+}
+
+func (o *Outbox) post(ctx context.Context, request traqapi.PostMessageRequest, channelID uuid.UUID) (uuid.UUID, error) {
+	res, err := o.c.postMessage(ctx, request, channelID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	switch res := res.(type) {
+	case *traqapi.Message:
+		return res.GetID(), nil
+
+	case *traqapi.PostMessageBadRequest:
+		return uuid.Nil, errors.New("bad request")
+
+	case *traqapi.PostMessageNotFound:
+		return uuid.Nil, errors.New("not found")
+
+	default:
+		return uuid.Nil, fmt.Errorf("unreachable error")
+	}
+}
+
+// findPosted looks for the message with the nonce among the latest messages of the channel.
+func (o *Outbox) findPosted(ctx context.Context, channelID, nonce uuid.UUID) (messageID uuid.UUID, found bool, err error) {
+	defer wrapf(&err, "find message %s in channel %s", nonce, channelID)
+
+	messages, err := o.c.getMessages(ctx, traqapi.GetMessagesParams{
+		ChannelId: channelID,
+		Limit:     traqapi.NewOptInt(postedLookupLimit),
+		Order:     traqapi.NewOptOrderInQuery(traqapi.OrderInQueryDesc),
+	})
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	for _, message := range messages {
+		if got, ok := message.GetNonce().Get(); ok && got == nonce.String() {
+			return message.GetID(), true, nil
+		}
+	}
+
+	return uuid.Nil, false, nil
+}
+
+// isDialError reports whether err happened while connecting, e.g. because the
+// connection was refused, so that the request certainly did not reach traQ.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (o *Outbox) notify(channelID uuid.UUID) {
+	o.mu.Lock()
+	o.updated[channelID] = struct{}{}
+	o.mu.Unlock()
+
+	select {
+	case o.updates <- struct{}{}:
+	default:
+		// a signal is pending and TakeUpdated will return channelID with the others
+	}
+}
@@ -0,0 +1,117 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+func newPinsStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, []traqapi.Pin], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, channelID uuid.UUID) (pins []traqapi.Pin, err error) {
+		defer wrapf(&err, "get pins from traQ for channel %s", channelID)
+
+		res, err := traqClient.GetChannelPins(ctx, traqapi.GetChannelPinsParams{
+			ChannelId: channelID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.GetChannelPinsOKApplicationJSON:
+			return *res, nil
+
+		case *traqapi.GetChannelPinsNotFound:
+			return nil, errors.New("not found")
+
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
+
+// CreatePin pins a message and updates the cached messages and pins of the channel.
+func (c *Context) CreatePin(ctx context.Context, channelID, messageID uuid.UUID) error {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	res, err := c.client.CreatePin(ctx, traqapi.CreatePinParams{
+		MessageId: messageID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return fmt.Errorf("pin message %s: %w", messageID, err)
+	}
+
+	switch res.(type) {
+	case *traqapi.MessagePin:
+	case *traqapi.CreatePinBadRequest:
+		return errors.New("pin message: bad request (already pinned, or too many pins)")
+	case *traqapi.CreatePinNotFound:
+		return errors.New("pin message: not found")
+	default:
+		return errors.New("pin message: unreachable error")
+	}
+
+	c.setPinned(channelID, messageID, true)
+
+	return nil
+}
+
+// RemovePin unpins a message and updates the cached messages and pins of the channel.
+func (c *Context) RemovePin(ctx context.Context, channelID, messageID uuid.UUID) error {
+	if c.IsOffline() {
+		return ErrOffline
+	}
+
+	res, err := c.client.RemovePin(ctx, traqapi.RemovePinParams{
+		MessageId: messageID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return fmt.Errorf("unpin message %s: %w", messageID, err)
+	}
+
+	switch res.(type) {
+	case *traqapi.RemovePinNoContent:
+	case *traqapi.RemovePinBadRequest:
+		return errors.New("unpin message: bad request")
+	case *traqapi.RemovePinNotFound:
+		return errors.New("unpin message: not found")
+	default:
+		return errors.New("unpin message: unreachable error")
+	}
+
+	c.setPinned(channelID, messageID, false)
+
+	return nil
+}
+
+func (c *Context) setPinned(channelID, messageID uuid.UUID, pinned bool) {
+	c.updateCachedMessages(channelID, func(messages []traqapi.Message) []traqapi.Message {
+		for i := range messages {
+			if messages[i].GetID() == messageID {
+				messages[i].SetPinned(pinned)
+			}
+		}
+
+		return messages
+	})
+
+	c.Pins.Forget(channelID)
+}
@@ -0,0 +1,50 @@
+package traqapiext
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+func newOnlineUsersStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, map[uuid.UUID]struct{}], error) {
+	// shorter than the presence refresh of the header, which relies on Get refreshing
+	// stale users in the background, as users come and go
+	freshFor := time.Second * 15
+	ttl := time.Minute * 5
+
+	return sc.New(func(ctx context.Context, _ struct{}) (online map[uuid.UUID]struct{}, err error) {
+		defer wrapf(&err, "get online users from traQ")
+
+		userIDs, err := traqClient.GetOnlineUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		online = make(map[uuid.UUID]struct{}, len(userIDs))
+		for _, userID := range userIDs {
+			id, err := uuid.Parse(userID)
+			if err != nil {
+				continue
+			}
+
+			online[id] = struct{}{}
+		}
+
+		return online, nil
+	}, freshFor, ttl)
+}
+
+// IsOnline reports whether the user is connected to traQ, looking it up in the
+// OnlineUsers cache without fetching it. Users are offline while it is not loaded.
+func (c *Context) IsOnline(userID uuid.UUID) bool {
+	online, ok := c.OnlineUsers.GetIfExists(struct{}{})
+	if !ok {
+		return false
+	}
+
+	_, ok = online[userID]
+	return ok
+}
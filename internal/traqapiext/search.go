@@ -0,0 +1,280 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+// SearchPageSize is the number of hits fetched per page of search results.
+const SearchPageSize = 20
+
+// ParseSearchQuery converts a query such as
+//
+//	from:@alice in:#general has:image before:2026-01-01 lunch
+//
+// into SearchMessages parameters. The filters are:
+//
+//	from:@user, to:@user  (repeatable)
+//	in:#channel/path
+//	citation:<message ID or link>
+//	bot:true|false, is:bot
+//	has:url|attachments|image|video|audio
+//	after:<date>, before:<date>  (2006-01-02 in local time, or RFC 3339)
+//	sort:createdAt|-createdAt|updatedAt|-updatedAt
+//
+// Other words, including "quoted phrases", make up the search word.
+// Users and channels are looked up in the caches, which must be loaded.
+func (c *Context) ParseSearchQuery(query string) (traqapi.SearchMessagesParams, error) {
+	var params traqapi.SearchMessagesParams
+	words := make([]string, 0)
+	for _, term := range splitSearchQuery(query) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" || strings.HasPrefix(term, `"`) {
+			words = append(words, term)
+			continue
+		}
+
+		key = strings.ToLower(key)
+		switch key {
+		case "from", "to":
+			user, ok := c.UserByName(strings.TrimPrefix(value, "@"))
+			if !ok {
+				return params, fmt.Errorf("unknown user: %s", value)
+			}
+
+			if key == "from" {
+				params.From = append(params.From, user.GetID())
+			} else {
+				params.To = append(params.To, user.GetID())
+			}
+
+		case "in":
+			channelID, ok := c.ChannelByPath(strings.TrimPrefix(value, "#"))
+			if !ok {
+				return params, fmt.Errorf("unknown channel: %s", value)
+			}
+
+			params.In = traqapi.NewOptUUID(channelID)
+
+		case "citation":
+			messageID, err := c.parseMessageRef(value)
+			if err != nil {
+				return params, err
+			}
+
+			params.Citation = traqapi.NewOptUUID(messageID)
+
+		case "bot":
+			bot, err := strconv.ParseBool(value)
+			if err != nil {
+				return params, fmt.Errorf("invalid bot filter: %s", value)
+			}
+
+			params.Bot = traqapi.NewOptBool(bot)
+
+		case "is":
+			if value != "bot" {
+				return params, fmt.Errorf("unknown filter: is:%s", value)
+			}
+
+			params.Bot = traqapi.NewOptBool(true)
+
+		case "has":
+			switch value {
+			case "url":
+				params.HasURL = traqapi.NewOptBool(true)
+			case "attachments", "attachment", "file":
+				params.HasAttachments = traqapi.NewOptBool(true)
+			case "image":
+				params.HasImage = traqapi.NewOptBool(true)
+			case "video":
+				params.HasVideo = traqapi.NewOptBool(true)
+			case "audio":
+				params.HasAudio = traqapi.NewOptBool(true)
+			default:
+				return params, fmt.Errorf("unknown filter: has:%s", value)
+			}
+
+		case "after", "before":
+			t, err := parseSearchTime(value)
+			if err != nil {
+				return params, fmt.Errorf("invalid date %s: %w", value, err)
+			}
+
+			if key == "after" {
+				params.After = traqapi.NewOptDateTime(t)
+			} else {
+				params.Before = traqapi.NewOptDateTime(t)
+			}
+
+		case "sort":
+			sort := traqapi.SearchMessagesSort(value)
+			if !slices.Contains([]traqapi.SearchMessagesSort{
+				traqapi.SearchMessagesSortCreatedAt,
+				traqapi.SearchMessagesSortMinusCreatedAt,
+				traqapi.SearchMessagesSortUpdatedAt,
+				traqapi.SearchMessagesSortMinusUpdatedAt,
+			}, sort) {
+				return params, fmt.Errorf("unknown sort: %s", value)
+			}
+
+			params.Sort = traqapi.NewOptSearchMessagesSort(sort)
+
+		default:
+			// e.g. URLs
+			words = append(words, term)
+		}
+	}
+
+	if len(words) > 0 {
+		params.Word = traqapi.NewOptString(strings.Join(words, " "))
+	}
+
+	return params, nil
+}
+
+// splitSearchQuery splits query at spaces outside double quotes.
+func splitSearchQuery(query string) []string {
+	terms := make([]string, 0)
+	var term strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			term.WriteRune(r)
+
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+
+		default:
+			term.WriteRune(r)
+		}
+	}
+
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+
+	return terms
+}
+
+func parseSearchTime(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseMessageRef parses a message ID or a link to a message on the traQ host.
+func (c *Context) parseMessageRef(value string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		return id, nil
+	}
+
+	if ids := c.FindMessageLinks(value); len(ids) > 0 {
+		return ids[0], nil
+	}
+
+	return uuid.Nil, fmt.Errorf("invalid message: %s", value)
+}
+
+// SearchMessages returns a page of messages matching params, newest first unless
+// params sets the order, and the total number of hits.
+func (c *Context) SearchMessages(ctx context.Context, params traqapi.SearchMessagesParams, page int) (hits []traqapi.Message, total int64, err error) {
+	if c.IsOffline() {
+		return nil, 0, ErrOffline
+	}
+
+	defer wrapf(&err, "search messages")
+
+	params.Limit = traqapi.NewOptInt(SearchPageSize)
+	params.Offset = traqapi.NewOptInt(page * SearchPageSize)
+	res, err := c.client.SearchMessages(ctx, params)
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return nil, 0, err
+	}
+
+	switch res := res.(type) {
+	case *traqapi.SearchMessagesOK:
+		return res.GetHits(), res.GetTotalHits(), nil
+
+	case *traqapi.SearchMessagesBadRequest:
+		return nil, 0, errors.New("bad request")
+
+	case *traqapi.SearchMessagesServiceUnavailable:
+		return nil, 0, errors.New("search is unavailable on this traQ")
+
+	default:
+		return nil, 0, fmt.Errorf("unreachable error")
+	}
+}
+
+// MessagesAround returns up to limit messages before and after a message in its channel,
+// newest first like the Messages cache. It is used to show messages that are too old
+// to be in the cache.
+func (c *Context) MessagesAround(ctx context.Context, channelID uuid.UUID, at time.Time, limit int) (messages []traqapi.Message, err error) {
+	defer wrapf(&err, "get messages around %s in channel %s", at, channelID)
+
+	older, err := c.getMessages(ctx, traqapi.GetMessagesParams{
+		ChannelId: channelID,
+		Until:     traqapi.NewOptDateTime(at),
+		Inclusive: traqapi.NewOptBool(true),
+		Limit:     traqapi.NewOptInt(limit),
+		Order:     traqapi.NewOptOrderInQuery(traqapi.OrderInQueryDesc),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newer, err := c.getMessages(ctx, traqapi.GetMessagesParams{
+		ChannelId: channelID,
+		Since:     traqapi.NewOptDateTime(at),
+		Limit:     traqapi.NewOptInt(limit),
+		Order:     traqapi.NewOptOrderInQuery(traqapi.OrderInQueryAsc),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(newer)
+
+	return append(newer, older...), nil
+}
+
+func (c *Context) getMessages(ctx context.Context, params traqapi.GetMessagesParams) ([]traqapi.Message, error) {
+	res, err := c.client.GetMessages(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res := res.(type) {
+	case *traqapi.GetMessagesOKHeaders:
+		return res.Response, nil
+
+	case *traqapi.GetMessagesBadRequest:
+		return nil, errors.New("bad request")
+
+	case *traqapi.GetMessagesNotFound:
+		return nil, errors.New("not found")
+
+	default:
+		return nil, fmt.Errorf("unreachable error")
+	}
+}
@@ -0,0 +1,280 @@
+package traqapiext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultStampImageCacheSize is the default size cap of the stamp image cache on disk.
+const DefaultStampImageCacheSize int64 = 64 << 20
+
+// StampImageStore caches stamp images on disk as stamp_images/<stampID>/<fileID>.<ext>.
+//
+// The file ID in the name is checked against the stamp metadata, so that an image
+// replaced with ChangeStampImage is fetched again. Entries are evicted in LRU order
+// once the total size exceeds maxSize.
+type StampImageStore struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	entries map[string]stampImageEntry // keyed by path relative to dir
+	size    int64
+}
+
+type stampImageEntry struct {
+	size       int64
+	lastUsedAt time.Time
+}
+
+// StampImageStats summarizes the contents of a StampImageStore.
+type StampImageStats struct {
+	Dir     string
+	Count   int
+	Size    int64
+	MaxSize int64
+}
+
+func NewStampImageStore(maxSize int64) (*StampImageStore, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &StampImageStore{
+		dir:     path.Join(cacheDir, "stamp_images"),
+		maxSize: maxSize,
+	}, nil
+}
+
+// Load returns the cached image for the stamp if it matches fileID.
+// Stale or undecodable entries are removed and reported as a miss.
+func (s *StampImageStore) Load(stampID, fileID uuid.UUID) (image.Image, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIndex(); err != nil {
+		slog.Warn("load stamp image cache index", "error", err)
+		return nil, false
+	}
+
+	stampDir := stampID.String()
+	entries, err := os.ReadDir(filepath.Join(s.dir, stampDir))
+	if err != nil || len(entries) == 0 {
+		return nil, false
+	}
+
+	filename := entries[0].Name()
+	name := path.Join(stampDir, filename)
+	if len(entries) > 1 || strings.TrimSuffix(filename, path.Ext(filename)) != fileID.String() {
+		s.removeLocked(stampID)
+		return nil, false
+	}
+
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, false
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		slog.Warn("decode cached stamp image, invalidating", "name", name, "error", err)
+		s.removeLocked(stampID)
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(filepath.Join(s.dir, name), now, now)
+	if entry, ok := s.entries[name]; ok {
+		entry.lastUsedAt = now
+		s.entries[name] = entry
+	}
+
+	return img, true
+}
+
+// Save stores the image data for the stamp, replacing older versions, and evicts
+// least recently used entries if the cache grows beyond its size cap.
+func (s *StampImageStore) Save(stampID, fileID uuid.UUID, ext string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIndex(); err != nil {
+		return fmt.Errorf("load stamp image cache index: %w", err)
+	}
+
+	if err := s.removeLocked(stampID); err != nil {
+		return fmt.Errorf("remove old stamp image: %w", err)
+	}
+
+	stampDir := stampID.String()
+	if err := os.MkdirAll(filepath.Join(s.dir, stampDir), 0o755); err != nil {
+		return fmt.Errorf("create stamp image cache subdir (%s): %w", stampDir, err)
+	}
+
+	name := path.Join(stampDir, fmt.Sprintf("%s.%s", fileID, ext))
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("write stamp image (%s): %w", name, err)
+	}
+
+	s.entries[name] = stampImageEntry{
+		size:       int64(len(data)),
+		lastUsedAt: time.Now(),
+	}
+	s.size += int64(len(data))
+
+	s.evictLocked()
+
+	return nil
+}
+
+// Invalidate removes the cached image for the stamp.
+func (s *StampImageStore) Invalidate(stampID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIndex(); err != nil {
+		return fmt.Errorf("load stamp image cache index: %w", err)
+	}
+
+	return s.removeLocked(stampID)
+}
+
+func (s *StampImageStore) Stats() (StampImageStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIndex(); err != nil {
+		return StampImageStats{}, fmt.Errorf("load stamp image cache index: %w", err)
+	}
+
+	return StampImageStats{
+		Dir:     s.dir,
+		Count:   len(s.entries),
+		Size:    s.size,
+		MaxSize: s.maxSize,
+	}, nil
+}
+
+// Clean removes every cached stamp image.
+func (s *StampImageStore) Clean() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("remove stamp image cache dir (%s): %w", s.dir, err)
+	}
+
+	s.entries = map[string]stampImageEntry{}
+	s.size = 0
+
+	return nil
+}
+
+// loadIndex scans the cache dir once to learn the size and last use of each entry.
+func (s *StampImageStore) loadIndex() error {
+	if s.entries != nil {
+		return nil
+	}
+
+	entries := map[string]stampImageEntry{}
+	var size int64
+	err := filepath.WalkDir(s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+
+		entries[filepath.ToSlash(name)] = stampImageEntry{
+			size:       info.Size(),
+			lastUsedAt: info.ModTime(),
+		}
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk stamp image cache dir (%s): %w", s.dir, err)
+	}
+
+	s.entries = entries
+	s.size = size
+
+	return nil
+}
+
+func (s *StampImageStore) removeLocked(stampID uuid.UUID) error {
+	stampDir := stampID.String()
+	for name, entry := range s.entries {
+		if path.Dir(name) == stampDir {
+			s.size -= entry.size
+			delete(s.entries, name)
+		}
+	}
+
+	if err := os.RemoveAll(filepath.Join(s.dir, stampDir)); err != nil {
+		return fmt.Errorf("remove stamp image cache subdir (%s): %w", stampDir, err)
+	}
+
+	return nil
+}
+
+func (s *StampImageStore) evictLocked() {
+	if s.maxSize <= 0 || s.size <= s.maxSize {
+		return
+	}
+
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+
+	slices.SortFunc(names, func(a, b string) int {
+		return s.entries[a].lastUsedAt.Compare(s.entries[b].lastUsedAt)
+	})
+
+	for _, name := range names {
+		if s.size <= s.maxSize {
+			break
+		}
+
+		stampID, err := uuid.Parse(path.Dir(name))
+		if err != nil {
+			continue
+		}
+
+		if err := s.removeLocked(stampID); err != nil {
+			slog.Warn("evict stamp image", "name", name, "error", err)
+		}
+	}
+}
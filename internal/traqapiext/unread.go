@@ -0,0 +1,89 @@
+package traqapiext
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+func newChannelSubscriptionsStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, map[uuid.UUID]traqapi.ChannelSubscribeLevel], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 30
+
+	return sc.New(func(ctx context.Context, _ struct{}) (levels map[uuid.UUID]traqapi.ChannelSubscribeLevel, err error) {
+		defer wrapf(&err, "get channel subscriptions from traQ")
+
+		subscriptions, err := traqClient.GetMyChannelSubscriptions(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		levels = make(map[uuid.UUID]traqapi.ChannelSubscribeLevel, len(subscriptions))
+		for _, subscription := range subscriptions {
+			levels[subscription.GetChannelId()] = subscription.GetLevel()
+		}
+
+		return levels, nil
+	}, freshFor, ttl)
+}
+
+func newNotifyCitationStore(traqClient *traqapi.Client) (*sc.Cache[struct{}, bool], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 30
+
+	return sc.New(func(ctx context.Context, _ struct{}) (notify bool, err error) {
+		defer wrapf(&err, "get citation notification setting from traQ")
+
+		res, err := traqClient.GetMyNotifyCitation(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		return res.GetNotifyCitation(), nil
+	}, freshFor, ttl)
+}
+
+// UnreadChannels returns the channels with unread messages. It is not cached,
+// as it is polled for new messages.
+func (c *Context) UnreadChannels(ctx context.Context) (unreads []traqapi.UnreadChannel, err error) {
+	if c.IsOffline() {
+		return nil, ErrOffline
+	}
+
+	defer wrapf(&err, "get unread channels")
+
+	unreads, err = c.client.GetMyUnreadChannels(ctx)
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return nil, err
+	}
+
+	return unreads, nil
+}
+
+// LatestMessage returns the newest message in the channel, bypassing the Messages cache.
+func (c *Context) LatestMessage(ctx context.Context, channelID uuid.UUID) (message traqapi.Message, err error) {
+	defer wrapf(&err, "get latest message in channel %s", channelID)
+
+	messages, err := c.getMessages(ctx, traqapi.GetMessagesParams{
+		ChannelId: channelID,
+		Limit:     traqapi.NewOptInt(1),
+		Order:     traqapi.NewOptOrderInQuery(traqapi.OrderInQueryDesc),
+	})
+	if err != nil {
+		return traqapi.Message{}, err
+	}
+
+	if len(messages) == 0 {
+		return traqapi.Message{}, fmt.Errorf("no messages")
+	}
+
+	return messages[0], nil
+}
@@ -0,0 +1,137 @@
+package traqapiext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/motoki317/sc"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+)
+
+func newUserDetailsStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, *traqapi.UserDetail], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, userID uuid.UUID) (user *traqapi.UserDetail, err error) {
+		defer wrapf(&err, "get user %s from traQ", userID)
+
+		res, err := traqClient.GetUser(ctx, traqapi.GetUserParams{
+			UserId: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.UserDetail:
+			return res, nil
+
+		case *traqapi.GetUserNotFound:
+			return nil, errors.New("not found")
+
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
+
+func newUserStatsStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, *traqapi.UserStats], error) {
+	freshFor := time.Minute * 5
+	ttl := time.Minute * 10
+
+	return sc.New(func(ctx context.Context, userID uuid.UUID) (stats *traqapi.UserStats, err error) {
+		defer wrapf(&err, "get stats of user %s from traQ", userID)
+
+		res, err := traqClient.GetUserStats(ctx, traqapi.GetUserStatsParams{
+			UserId: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch res := res.(type) {
+		case *traqapi.UserStats:
+			return res, nil
+
+		case *traqapi.GetUserStatsNotFound:
+			return nil, errors.New("not found")
+
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+	}, freshFor, ttl)
+}
+
+func newUserIconsStore(traqClient *traqapi.Client) (*sc.Cache[uuid.UUID, image.Image], error) {
+	freshFor := time.Hour
+	ttl := time.Hour * 2
+
+	return sc.New(func(ctx context.Context, userID uuid.UUID) (img image.Image, err error) {
+		defer wrapf(&err, "get icon of user %s", userID)
+
+		res, err := traqClient.GetUserIcon(ctx, traqapi.GetUserIconParams{
+			UserId: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var r io.Reader
+		switch res := res.(type) {
+		case *traqapi.GetUserIconNotFound:
+			return nil, errors.New("not found")
+		case *traqapi.GetUserIconOKImageGIF:
+			r = res
+		case *traqapi.GetUserIconOKImageJpeg:
+			r = res
+		case *traqapi.GetUserIconOKImagePNG:
+			r = res
+		default:
+			return nil, fmt.Errorf("unreachable error")
+		}
+
+		img, _, err = image.Decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("decode image: %w", err)
+		}
+
+		return img, nil
+	}, freshFor, ttl)
+}
+
+// DMChannelID returns the ID of the direct message channel with the user,
+// creating it on traQ if it does not exist yet.
+func (c *Context) DMChannelID(ctx context.Context, userID uuid.UUID) (channelID uuid.UUID, err error) {
+	if c.IsOffline() {
+		return uuid.Nil, ErrOffline
+	}
+
+	defer wrapf(&err, "get dm channel with user %s", userID)
+
+	res, err := c.client.GetUserDMChannel(ctx, traqapi.GetUserDMChannelParams{
+		UserId: userID,
+	})
+	if err != nil {
+		if IsUnreachable(err) {
+			c.setOffline(true)
+		}
+
+		return uuid.Nil, err
+	}
+
+	switch res := res.(type) {
+	case *traqapi.DMChannel:
+		return res.GetID(), nil
+
+	case *traqapi.GetUserDMChannelNotFound:
+		return uuid.Nil, errors.New("not found")
+
+	default:
+		return uuid.Nil, fmt.Errorf("unreachable error")
+	}
+}
@@ -0,0 +1,50 @@
+package tui
+
+import "os"
+
+type size struct {
+	w, h int
+}
+
+type layout struct {
+	header         size
+	sidebar        size
+	channelContent size
+	messageInput   size
+}
+
+// computeLayout splits the screen into the panes. Sizes exclude the borders.
+func computeLayout(w, h int) layout {
+	if os.Getenv("DEBUG") != "" {
+		h -= 2
+	}
+
+	// Layout calculation
+	// ---------------------
+	// |       header      |
+	// |-------------------|
+	// |    |   content    |
+	// | ct |              |
+	// |    |--------------|
+	// |    | messageInput |
+	// ---------------------
+
+	headerHeight := 3
+	mainHeight := h - headerHeight
+	sidebarHeight := mainHeight
+	channelContentHeight := mainHeight * 7 / 10
+	messageInputHeight := mainHeight - channelContentHeight
+
+	headerWidth := w
+	sidebarWidth := w * 2 / 10
+	messageInputWidth := w - sidebarWidth
+	channelContentWidth := w - sidebarWidth
+	padding := 2
+
+	return layout{
+		header:         size{headerWidth - padding, headerHeight - padding},
+		sidebar:        size{sidebarWidth - padding, sidebarHeight - padding},
+		channelContent: size{channelContentWidth - padding, channelContentHeight - padding},
+		messageInput:   size{messageInputWidth - padding, messageInputHeight - padding},
+	}
+}
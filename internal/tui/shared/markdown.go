@@ -0,0 +1,140 @@
+package shared
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+)
+
+// NewMarkdownRenderer creates a glamour renderer for message content in a pane of width w.
+func NewMarkdownRenderer(w int) *glamour.TermRenderer {
+	renderer, _ := glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(w-10),
+	)
+
+	return renderer
+}
+
+// RenderMarkdown renders traQ-flavoured markdown and styles mentions, channel links,
+// stamps and spoilers in the output.
+func RenderMarkdown(
+	traqContext *traqapiext.Context,
+	renderer *glamour.TermRenderer,
+	styles ChannelContentStyles,
+	content string,
+	opts traqapiext.MarkdownOptions,
+) string {
+	opts.MarkTokens = true
+	md := traqContext.ParseMarkdown(content, opts)
+
+	rendered, err := renderer.Render(md.Content)
+	if err != nil {
+		return content
+	}
+
+	return styleTokens(rendered, styles)
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// styleTokens styles the text between the token markers in rendered output and
+// removes the markers. Escape sequences are left intact, and the style glamour set
+// before a token is restored after it.
+func styleTokens(rendered string, styles ChannelContentStyles) string {
+	var b strings.Builder
+	// active holds the SGR sequences since the last reset
+	active := ""
+	// kinds holds the tokens the text is in, innermost last
+	kinds := make([]traqapiext.TokenKind, 0, 2)
+
+	var text strings.Builder
+	flush := func() {
+		defer text.Reset()
+
+		style, ok := tokenStyle(styles, kinds)
+		if !ok {
+			b.WriteString(text.String())
+			return
+		}
+
+		// styles are rendered per line, as lipgloss pads multi-line text
+		for i, line := range strings.Split(text.String(), "\n") {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+
+			// e.g. padding and margins of lines a wrapped token spans
+			if strings.TrimSpace(line) == "" {
+				b.WriteString(line)
+				continue
+			}
+
+			b.WriteString(style.Render(line) + active)
+		}
+	}
+
+	writeText := func(s string) {
+		for _, r := range s {
+			if kind, ok := traqapiext.TokenMarkerKind(r); ok {
+				flush()
+				kinds = append(kinds, kind)
+				continue
+			}
+
+			if r == traqapiext.TokenEnd {
+				flush()
+				if len(kinds) > 0 {
+					kinds = kinds[:len(kinds)-1]
+				}
+				continue
+			}
+
+			text.WriteRune(r)
+		}
+
+		flush()
+	}
+
+	last := 0
+	for _, loc := range ansiPattern.FindAllStringIndex(rendered, -1) {
+		writeText(rendered[last:loc[0]])
+
+		sequence := rendered[loc[0]:loc[1]]
+		b.WriteString(sequence)
+		switch {
+		case sequence == "\x1b[0m" || sequence == "\x1b[m":
+			active = ""
+		case strings.HasSuffix(sequence, "m"):
+			active += sequence
+		}
+
+		last = loc[1]
+	}
+	writeText(rendered[last:])
+
+	return b.String()
+}
+
+// tokenStyle returns the style of the innermost token.
+func tokenStyle(styles ChannelContentStyles, kinds []traqapiext.TokenKind) (lipgloss.Style, bool) {
+	if len(kinds) == 0 {
+		return lipgloss.Style{}, false
+	}
+
+	switch kinds[len(kinds)-1] {
+	case traqapiext.TokenUser, traqapiext.TokenGroup:
+		return styles.Mention, true
+	case traqapiext.TokenChannel:
+		return styles.ChannelLink, true
+	case traqapiext.TokenStamp:
+		return styles.Stamp, true
+	case traqapiext.TokenSpoiler:
+		return styles.Spoiler, true
+	default:
+		return lipgloss.Style{}, false
+	}
+}
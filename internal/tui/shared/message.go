@@ -17,7 +17,86 @@ type (
 	FocusMessageInputMsg struct {
 		ChannelID uuid.UUID
 	}
-	MessageSentMsg struct {
+
+	// RestoreDraftMsg puts content back into the message input of the channel, e.g. to
+	// edit a message that failed to be sent.
+	RestoreDraftMsg struct {
+		ChannelID uuid.UUID
+		Content   string
+	}
+
+	// EditMessageMsg opens a message in the message input to edit it.
+	EditMessageMsg struct {
+		ChannelID uuid.UUID
 		MessageID uuid.UUID
+		Content   string
+	}
+
+	// MessageEditedMsg is sent when a message has been edited or deleted.
+	MessageEditedMsg struct {
+		ChannelID uuid.UUID
+		MessageID uuid.UUID
+	}
+
+	// JumpToMessageMsg opens the channel of a message scrolled to it, e.g. from search results.
+	JumpToMessageMsg struct {
+		ChannelID uuid.UUID
+		MessageID uuid.UUID
+	}
+
+	// ReturnToChannelContentMsg focuses the channel content, e.g. after choosing a clip folder.
+	ReturnToChannelContentMsg struct{}
+
+	// OpenClipsMsg opens the list of clip folders.
+	OpenClipsMsg struct{}
+
+	// ClipMessageMsg asks for a clip folder to clip a message into.
+	ClipMessageMsg struct {
+		MessageID uuid.UUID
+	}
+
+	// OpenClipFolderMsg shows the messages clipped in a folder in the channel content.
+	OpenClipFolderMsg struct {
+		Folder traqapi.ClipFolder
+	}
+
+	// OpenChannelInfoMsg opens the channel info panel of a channel.
+	OpenChannelInfoMsg struct {
+		ChannelID uuid.UUID
+	}
+
+	// ChannelTopicEditedMsg is sent when the topic of a channel has been changed.
+	ChannelTopicEditedMsg struct {
+		ChannelID uuid.UUID
+	}
+
+	// OpenProfileMsg opens the profile of the first user, e.g. the author of a message,
+	// and lets the others, e.g. the users mentioned in it, be browsed.
+	OpenProfileMsg struct {
+		UserIDs []uuid.UUID
+	}
+
+	// MentionUserMsg adds a mention of the user to the message input of the channel.
+	MentionUserMsg struct {
+		ChannelID uuid.UUID
+		UserID    uuid.UUID
+		Name      string
+	}
+
+	// OutboxUpdatedMsg is sent when the queued messages of some channels changed.
+	OutboxUpdatedMsg struct {
+		ChannelIDs []uuid.UUID
+	}
+
+	// PresenceUpdatedMsg is sent when the online users have been refetched.
+	PresenceUpdatedMsg struct{}
+
+	// DraftsChangedMsg is sent when a channel gets or loses a draft.
+	DraftsChangedMsg struct{}
+
+	// CacheRevalidatedMsg is sent when a value served from the disk cache has been refreshed.
+	CacheRevalidatedMsg struct {
+		Bucket string
+		Key    string
 	}
 )
@@ -21,22 +21,126 @@ type HeaderStyles struct {
 	Title    lipgloss.Style
 	Host     lipgloss.Style
 	Username lipgloss.Style
+	Offline  lipgloss.Style
+	// Topic styles the channel and topic of the open channel
+	Topic lipgloss.Style
+}
+
+// ChannelTreeStyles defines styling for channelTree component
+type ChannelTreeStyles struct {
+	// Notice styles the result of channel management actions and confirmations
+	Notice lipgloss.Style
+	Error  lipgloss.Style
 }
 
 // ChannelContentStyles defines styling for channelContent component
 type ChannelContentStyles struct {
 	Time       lipgloss.Style
 	MessageBox lipgloss.Style
-	Username   lipgloss.Style
-	Separator  lipgloss.Style
+	// SelectedMessageBox replaces MessageBox for the selected message
+	SelectedMessageBox lipgloss.Style
+	Username           lipgloss.Style
+	Separator          lipgloss.Style
+	// Mention styles @user and @group mentions in message content
+	Mention     lipgloss.Style
+	ChannelLink lipgloss.Style
+	Stamp       lipgloss.Style
+	Spoiler     lipgloss.Style
+	// Quote styles messages cited by message links
+	Quote lipgloss.Style
+	// Preview styles OGP link preview cards
+	Preview      lipgloss.Style
+	PreviewSite  lipgloss.Style
+	PreviewTitle lipgloss.Style
+	// Highlight styles matches of the find query
+	Highlight lipgloss.Style
+	// Pending styles the delivery state of messages being sent
+	Pending lipgloss.Style
+	Failed  lipgloss.Style
+}
+
+// MessageInputStyles defines styling for messageInput component
+type MessageInputStyles struct {
+	// Completion styles the completion popup
+	Completion         lipgloss.Style
+	CompletionItem     lipgloss.Style
+	CompletionSelected lipgloss.Style
+	CompletionDetail   lipgloss.Style
+	// Banner styles the notice shown above the editor while editing a message
+	Banner lipgloss.Style
+	// Preview styles the rendered preview next to the editor
+	Preview lipgloss.Style
+}
+
+// SearchStyles defines styling for search component
+type SearchStyles struct {
+	// Meta styles the channel, author and time of hits, and hints
+	Meta        lipgloss.Style
+	Hit         lipgloss.Style
+	SelectedHit lipgloss.Style
+	Error       lipgloss.Style
+}
+
+// ActivityStyles defines styling for activity component
+type ActivityStyles struct {
+	Title lipgloss.Style
+	// Meta styles the channel, author and time of entries, and hints
+	Meta          lipgloss.Style
+	Entry         lipgloss.Style
+	SelectedEntry lipgloss.Style
+	Error         lipgloss.Style
+}
+
+// ClipsStyles defines styling for clips component
+type ClipsStyles struct {
+	Title lipgloss.Style
+	// Meta styles the descriptions and dates of folders, and hints
+	Meta           lipgloss.Style
+	Folder         lipgloss.Style
+	SelectedFolder lipgloss.Style
+	Error          lipgloss.Style
+}
+
+// ChannelInfoStyles defines styling for channelInfo component
+type ChannelInfoStyles struct {
+	Title lipgloss.Style
+	// Section styles the headings of topic, stats, viewers, subscribers and bots
+	Section lipgloss.Style
+	Meta    lipgloss.Style
+	Error   lipgloss.Style
+}
+
+// ProfileStyles defines styling for profile component
+type ProfileStyles struct {
+	DisplayName lipgloss.Style
+	// Section styles the headings of bio, groups, tags and stats
+	Section lipgloss.Style
+	Meta    lipgloss.Style
+	Error   lipgloss.Style
+}
+
+// PresenceStyles defines styling for the dots that show whether users are online
+type PresenceStyles struct {
+	Online  lipgloss.Style
+	Offline lipgloss.Style
 }
 
 // Theme aggregates all style definitions
 type Theme struct {
+	// Name identifies the theme, e.g. in render cache keys
+	Name           string
 	Colors         Colors
 	Border         BorderStyles
 	Header         HeaderStyles
+	ChannelTree    ChannelTreeStyles
 	ChannelContent ChannelContentStyles
+	MessageInput   MessageInputStyles
+	Search         SearchStyles
+	Activity       ActivityStyles
+	Clips          ClipsStyles
+	ChannelInfo    ChannelInfoStyles
+	Profile        ProfileStyles
+	Presence       PresenceStyles
 }
 
 // DefaultTheme returns the default color scheme
@@ -49,6 +153,7 @@ func DefaultTheme() Theme {
 	}
 
 	return Theme{
+		Name:   "default",
 		Colors: colors,
 		Border: BorderStyles{
 			Normal:  lipgloss.NewStyle().Border(lipgloss.RoundedBorder()),
@@ -58,6 +163,12 @@ func DefaultTheme() Theme {
 			Title:    lipgloss.NewStyle().Bold(true).Italic(true),
 			Host:     lipgloss.NewStyle().Bold(true),
 			Username: lipgloss.NewStyle().Bold(true),
+			Offline:  lipgloss.NewStyle().Foreground(colors.Muted).Italic(true),
+			Topic:    lipgloss.NewStyle().Foreground(colors.Muted),
+		},
+		ChannelTree: ChannelTreeStyles{
+			Notice: lipgloss.NewStyle().Foreground(colors.Muted),
+			Error:  lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
 		},
 		ChannelContent: ChannelContentStyles{
 			Time: lipgloss.NewStyle().Foreground(colors.Accent).PaddingRight(1),
@@ -66,10 +177,94 @@ func DefaultTheme() Theme {
 				BorderLeft(true).
 				BorderForeground(colors.Muted).
 				PaddingLeft(1),
-			Username:  lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
-			Separator: lipgloss.NewStyle().Foreground(colors.Muted),
+			SelectedMessageBox: lipgloss.NewStyle().
+				BorderStyle(lipgloss.Border{Left: "┃"}).
+				BorderLeft(true).
+				BorderForeground(colors.Primary).
+				PaddingLeft(1),
+			Username:    lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			Separator:   lipgloss.NewStyle().Foreground(colors.Muted),
+			Mention:     lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			ChannelLink: lipgloss.NewStyle().Foreground(colors.Primary).Underline(true),
+			Stamp:       lipgloss.NewStyle().Foreground(colors.Accent),
+			Spoiler:     lipgloss.NewStyle().Foreground(colors.Muted),
+			Quote: lipgloss.NewStyle().
+				BorderStyle(lipgloss.Border{Left: "┃"}).
+				BorderLeft(true).
+				BorderForeground(colors.Accent).
+				PaddingLeft(1).
+				MarginLeft(2),
+			Preview: lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(colors.Muted).
+				PaddingLeft(1).
+				PaddingRight(1),
+			PreviewSite:  lipgloss.NewStyle().Foreground(colors.Muted),
+			PreviewTitle: lipgloss.NewStyle().Bold(true),
+			Highlight:    lipgloss.NewStyle().Reverse(true),
+			Pending:      lipgloss.NewStyle().Foreground(colors.Muted).Italic(true),
+			Failed:       lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		},
+		MessageInput: MessageInputStyles{
+			Completion: lipgloss.NewStyle().
+				BorderStyle(lipgloss.Border{Top: "─"}).
+				BorderTop(true).
+				BorderForeground(colors.Muted),
+			CompletionItem:     lipgloss.NewStyle().PaddingLeft(1),
+			CompletionSelected: lipgloss.NewStyle().PaddingLeft(1).Foreground(colors.Primary).Bold(true),
+			CompletionDetail:   lipgloss.NewStyle().Foreground(colors.Muted).PaddingLeft(1),
+			Banner:             lipgloss.NewStyle().Foreground(colors.Primary).Bold(true).PaddingLeft(1),
+			Preview: lipgloss.NewStyle().
+				BorderStyle(lipgloss.Border{Left: "│"}).
+				BorderLeft(true).
+				BorderForeground(colors.Muted).
+				PaddingLeft(1),
+		},
+		Search: SearchStyles{
+			Meta:        lipgloss.NewStyle().Foreground(colors.Muted),
+			Hit:         lipgloss.NewStyle(),
+			SelectedHit: lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			Error:       lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
 		},
+		Activity: ActivityStyles{
+			Title:         lipgloss.NewStyle().Bold(true),
+			Meta:          lipgloss.NewStyle().Foreground(colors.Muted),
+			Entry:         lipgloss.NewStyle(),
+			SelectedEntry: lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			Error:         lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		},
+		Clips: ClipsStyles{
+			Title:          lipgloss.NewStyle().Bold(true),
+			Meta:           lipgloss.NewStyle().Foreground(colors.Muted),
+			Folder:         lipgloss.NewStyle(),
+			SelectedFolder: lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			Error:          lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		},
+		ChannelInfo: ChannelInfoStyles{
+			Title:   lipgloss.NewStyle().Bold(true),
+			Section: lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			Meta:    lipgloss.NewStyle().Foreground(colors.Muted),
+			Error:   lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		},
+		Profile: ProfileStyles{
+			DisplayName: lipgloss.NewStyle().Bold(true),
+			Section:     lipgloss.NewStyle().Foreground(colors.Primary).Bold(true),
+			Meta:        lipgloss.NewStyle().Foreground(colors.Muted),
+			Error:       lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		},
+		Presence: PresenceStyles{
+			Online:  lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+			Offline: lipgloss.NewStyle().Foreground(colors.Muted),
+		},
+	}
+}
+
+// PresenceDot renders a dot that is filled if the user is online
+func (t Theme) PresenceDot(online bool) string {
+	if online {
+		return t.Presence.Online.Render("●")
 	}
+	return t.Presence.Offline.Render("○")
 }
 
 // WithBorder applies border style based on focus state
@@ -2,28 +2,63 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/notify"
 	"github.com/ras0q/lazytraq/internal/traqapi"
 	"github.com/ras0q/lazytraq/internal/traqapiext"
 	"github.com/ras0q/lazytraq/internal/tui/shared"
+	"github.com/ras0q/lazytraq/internal/tui/viewmodel/activity"
 	"github.com/ras0q/lazytraq/internal/tui/viewmodel/channelcontent"
+	"github.com/ras0q/lazytraq/internal/tui/viewmodel/channelinfo"
 	"github.com/ras0q/lazytraq/internal/tui/viewmodel/channeltree"
+	"github.com/ras0q/lazytraq/internal/tui/viewmodel/clips"
 	"github.com/ras0q/lazytraq/internal/tui/viewmodel/header"
 	"github.com/ras0q/lazytraq/internal/tui/viewmodel/messageinput"
+	"github.com/ras0q/lazytraq/internal/tui/viewmodel/profile"
+	"github.com/ras0q/lazytraq/internal/tui/viewmodel/search"
+)
+
+// sequenceDrawDelay is how long the escape sequences of notifications are kept in the
+// view, so that the renderer draws them at least once.
+const sequenceDrawDelay = 100 * time.Millisecond
+
+type (
+	terminalSequenceMsg struct {
+		sequence string
+	}
+	terminalSequenceDrawnMsg struct {
+		seq int
+	}
 )
 
 type AppModel struct {
+	traqContext    *traqapiext.Context
 	theme          shared.Theme
 	header         *header.Model
 	channelTree    *channeltree.Model
 	messageInput   *messageinput.Model
 	channelContent *channelcontent.Model
+	search         *search.Model
+	activity       *activity.Model
+	clips          *clips.Model
+	channelInfo    *channelinfo.Model
+	profile        *profile.Model
+	notifier       *notify.Notifier
+	terminal       *notify.Terminal
 	Errors         []error
 
+	// sequences holds the escape sequences of notifications, written with the next
+	// frames until sequenceSeq is cleared.
+	sequences   string
+	sequenceSeq int
+
 	focus   focusArea
 	channel *traqapi.Channel
 }
@@ -35,81 +70,128 @@ const (
 	focusAreaSidebar
 	focusAreaMessageInput
 	focusAreaChannelContent
+	// focusAreaSearch shows the search pane in place of the channel content.
+	focusAreaSearch
+	// focusAreaActivity shows the activity timeline in place of the channel content.
+	focusAreaActivity
+	// focusAreaClips shows the clip folders in place of the channel content.
+	focusAreaClips
+	// focusAreaChannelInfo shows the channel info panel in place of the channel content.
+	focusAreaChannelInfo
+	// focusAreaProfile shows a user profile in place of the channel content.
+	focusAreaProfile
 )
 
-func NewAppModel(w, h int, apiHost string, securitySource *traqapiext.SecuritySource) (*AppModel, error) {
+// NewAppModel creates the root model. Notifications of new messages are sent to
+// sinks, and are turned off if there are none. The escape sequences of the terminal
+// sinks are taken from terminal and written with the screen.
+func NewAppModel(w, h int, apiHost string, securitySource *traqapiext.SecuritySource, sinks []notify.Sink, terminal *notify.Terminal) (*AppModel, error) {
 	traqContext, err := traqapiext.NewContext(apiHost, securitySource)
 	if err != nil {
 		return nil, fmt.Errorf("create traq context: %w", err)
 	}
 
-	if os.Getenv("DEBUG") != "" {
-		h -= 2
-	}
-
-	// Layout calculation
-	// ---------------------
-	// |       header      |
-	// |-------------------|
-	// |    |   content    |
-	// | ct |              |
-	// |    |--------------|
-	// |    | messageInput |
-	// ---------------------
-
-	headerHeight := 3
-	mainHeight := h - headerHeight
-	sidebarHeight := mainHeight
-	channelContentHeight := mainHeight * 7 / 10
-	messageInputHeight := mainHeight - channelContentHeight
-
-	headerWidth := w
-	sidebarWidth := w * 2 / 10
-	messageInputWidth := w - sidebarWidth
-	channelContentWidth := w - sidebarWidth
-	padding := 2
+	l := computeLayout(w, h)
 
 	theme := shared.DefaultTheme()
 
 	return &AppModel{
-		theme: theme,
+		traqContext: traqContext,
+		theme:       theme,
 		header: header.New(
-			headerWidth-padding,
-			headerHeight-padding,
+			l.header.w,
+			l.header.h,
 			apiHost,
 			traqContext,
 			theme,
 		),
 		channelTree: channeltree.New(
-			sidebarWidth-padding,
-			sidebarHeight-padding,
+			l.sidebar.w,
+			l.sidebar.h,
 			traqContext,
+			theme,
 		),
 		messageInput: messageinput.New(
-			messageInputWidth-padding,
-			messageInputHeight-padding,
+			l.messageInput.w,
+			l.messageInput.h,
 			traqContext,
+			theme,
 		),
 		channelContent: channelcontent.New(
-			channelContentWidth-padding,
-			channelContentHeight-padding,
+			l.channelContent.w,
+			l.channelContent.h,
+			traqContext,
+			theme,
+		),
+		search: search.New(
+			l.channelContent.w,
+			l.channelContent.h,
 			traqContext,
 			theme,
 		),
-		Errors:  make([]error, 0, 10),
-		focus:   focusAreaSidebar,
-		channel: nil,
+		activity: activity.New(
+			l.channelContent.w,
+			l.channelContent.h,
+			traqContext,
+			theme,
+		),
+		clips: clips.New(
+			l.channelContent.w,
+			l.channelContent.h,
+			traqContext,
+			theme,
+		),
+		channelInfo: channelinfo.New(
+			l.channelContent.w,
+			l.channelContent.h,
+			traqContext,
+			theme,
+		),
+		profile: profile.New(
+			l.channelContent.w,
+			l.channelContent.h,
+			traqContext,
+			theme,
+		),
+		notifier: notify.New(
+			notify.NewPollingSource(traqContext, notify.PollInterval),
+			notify.ContextPreferences(traqContext),
+			sinks,
+		),
+		terminal: terminal,
+		Errors:   make([]error, 0, 10),
+		focus:    focusAreaSidebar,
+		channel:  nil,
 	}, nil
 }
 
 var _ tea.Model = (*AppModel)(nil)
 
 func (m *AppModel) Init() tea.Cmd {
+	m.notifier.Start()
+
 	return tea.Batch(
 		m.header.Init(),
 		m.channelTree.Init(),
 		m.messageInput.Init(),
 		m.channelContent.Init(),
+		m.search.Init(),
+		m.activity.Init(),
+		m.clips.Init(),
+		m.channelInfo.Init(),
+		m.profile.Init(),
+		m.waitForRevalidationCmd(),
+		m.waitForOutboxUpdateCmd(),
+		m.waitForTerminalSequenceCmd(),
+	)
+}
+
+// Close saves the draft being edited and releases resources held by the model.
+func (m *AppModel) Close() error {
+	return errors.Join(
+		m.notifier.Close(),
+		m.messageInput.Close(),
+		m.traqContext.Close(),
 	)
 }
 
@@ -134,22 +216,167 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case shared.ReturnToSidebarMsg:
 		m.focus = focusAreaSidebar
 
+	case tea.WindowSizeMsg:
+		// NOTE: decrease padding
+		l := computeLayout(msg.Width, msg.Height-2)
+		m.header.SetSize(l.header.w, l.header.h)
+		m.channelTree.SetSize(l.sidebar.w, l.sidebar.h)
+		m.search.SetSize(l.channelContent.w, l.channelContent.h)
+		m.activity.SetSize(l.channelContent.w, l.channelContent.h)
+		m.clips.SetSize(l.channelContent.w, l.channelContent.h)
+		m.channelInfo.SetSize(l.channelContent.w, l.channelContent.h)
+		m.profile.SetSize(l.channelContent.w, l.channelContent.h)
+		cmds = append(cmds,
+			m.messageInput.SetSize(l.messageInput.w, l.messageInput.h),
+			m.channelContent.SetSize(l.channelContent.w, l.channelContent.h),
+		)
+
+	case shared.CacheRevalidatedMsg:
+		cmds = append(cmds, m.broadcast(msg), m.waitForRevalidationCmd())
+
+	case shared.OutboxUpdatedMsg:
+		cmds = append(cmds, m.broadcast(msg), m.waitForOutboxUpdateCmd())
+
+	case terminalSequenceMsg:
+		m.sequences += msg.sequence
+		m.sequenceSeq++
+		seq := m.sequenceSeq
+		cmds = append(cmds,
+			m.waitForTerminalSequenceCmd(),
+			tea.Tick(sequenceDrawDelay, func(time.Time) tea.Msg {
+				return terminalSequenceDrawnMsg{seq: seq}
+			}),
+		)
+
+	case terminalSequenceDrawnMsg:
+		// later sequences are cleared once they are drawn too
+		if msg.seq == m.sequenceSeq {
+			m.sequences = ""
+		}
+
+	case shared.RestoreDraftMsg, shared.EditMessageMsg:
+		m.focus = focusAreaMessageInput
+		cmds = append(cmds, m.broadcast(msg))
+
+	case shared.JumpToMessageMsg:
+		ctx := context.Background()
+		m.focus = focusAreaChannelContent
+		m.channel = m.findChannel(msg.ChannelID)
+		cmds = append(cmds,
+			m.channelContent.JumpToMessageCmd(ctx, msg.ChannelID, msg.MessageID),
+			m.header.ShowChannelCmd(ctx, msg.ChannelID),
+		)
+
+	case shared.OpenChannelInfoMsg:
+		m.focus = focusAreaChannelInfo
+		cmds = append(cmds, m.channelInfo.Open(msg.ChannelID))
+
+	case shared.OpenProfileMsg:
+		m.focus = focusAreaProfile
+		cmds = append(cmds, m.profile.Open(msg.UserIDs))
+
+	case shared.MentionUserMsg:
+		// the user cannot be mentioned without a channel to post to
+		if m.channel == nil || m.channel.Force {
+			break
+		}
+
+		m.focus = focusAreaMessageInput
+		msg.ChannelID = m.channel.ID
+		cmds = append(cmds, m.broadcast(msg))
+
+	case shared.ReturnToChannelContentMsg:
+		m.focus = focusAreaChannelContent
+
+	case shared.OpenClipsMsg:
+		m.focus = focusAreaClips
+		cmds = append(cmds, m.clips.Open())
+
+	case shared.ClipMessageMsg:
+		m.focus = focusAreaClips
+		cmds = append(cmds, m.clips.ChooseFolder(msg.MessageID))
+
+	case shared.OpenClipFolderMsg:
+		m.focus = focusAreaChannelContent
+		cmds = append(cmds, m.channelContent.ShowClipFolderCmd(context.Background(), msg.Folder))
+
 	case shared.OpenChannelMsg:
 		channel := msg.Target
 		if channel == nil {
 			break
 		}
 
+		ctx := context.Background()
 		m.focus = focusAreaChannelContent
 		m.channel = channel
 
-		cmd := m.channelContent.FetchMessagesCmd(context.Background(), channel.ID)
-		cmds = append(cmds, cmd)
+		cmds = append(cmds,
+			m.channelContent.FetchMessagesCmd(ctx, channel.ID),
+			m.header.ShowChannelCmd(ctx, channel.ID),
+		)
 
 	case tea.KeyMsg:
+		// the query may contain any letter
+		if m.focus == focusAreaSearch && m.search.Typing() && msg.String() != "ctrl+c" {
+			_search, cmd := m.search.Update(msg)
+			m.search = _search.(*search.Model)
+			cmds = append(cmds, cmd)
+
+			break
+		}
+
+		if m.focus == focusAreaClips && m.clips.CapturesKey(msg.String()) {
+			_clips, cmd := m.clips.Update(msg)
+			m.clips = _clips.(*clips.Model)
+			cmds = append(cmds, cmd)
+
+			break
+		}
+
+		if m.focus == focusAreaSidebar && m.channelTree.CapturesKey(msg.String()) {
+			_sidebar, cmd := m.channelTree.Update(msg)
+			m.channelTree = _sidebar.(*channeltree.Model)
+			cmds = append(cmds, cmd)
+
+			break
+		}
+
+		if m.focus == focusAreaChannelInfo && m.channelInfo.CapturesKey(msg.String()) {
+			_channelInfo, cmd := m.channelInfo.Update(msg)
+			m.channelInfo = _channelInfo.(*channelinfo.Model)
+			cmds = append(cmds, cmd)
+
+			break
+		}
+
+		if m.focus == focusAreaMessageInput && m.messageInput.CapturesKey(msg.String()) {
+			_messageInput, cmd := m.messageInput.Update(msg)
+			m.messageInput = _messageInput.(*messageinput.Model)
+			cmds = append(cmds, cmd)
+
+			break
+		}
+
+		if m.focus == focusAreaChannelContent && m.channelContent.CapturesKey(msg.String()) {
+			_channelContent, cmd := m.channelContent.Update(msg)
+			m.channelContent = _channelContent.(*channelcontent.Model)
+			cmds = append(cmds, cmd)
+
+			break
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
+		case "ctrl+f":
+			m.focus = focusAreaSearch
+			cmds = append(cmds, m.search.Open())
+		case "ctrl+a":
+			m.focus = focusAreaActivity
+			cmds = append(cmds, m.activity.Open())
+		case "ctrl+b":
+			m.focus = focusAreaClips
+			cmds = append(cmds, m.clips.Open())
 		case "n":
 			if m.channel.Force {
 				break
@@ -183,31 +410,137 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				_channelContent, cmd := m.channelContent.Update(msg)
 				m.channelContent = _channelContent.(*channelcontent.Model)
 				cmds = append(cmds, cmd)
+
+			case focusAreaSearch:
+				_search, cmd := m.search.Update(msg)
+				m.search = _search.(*search.Model)
+				cmds = append(cmds, cmd)
+
+			case focusAreaActivity:
+				_activity, cmd := m.activity.Update(msg)
+				m.activity = _activity.(*activity.Model)
+				cmds = append(cmds, cmd)
+
+			case focusAreaClips:
+				_clips, cmd := m.clips.Update(msg)
+				m.clips = _clips.(*clips.Model)
+				cmds = append(cmds, cmd)
+
+			case focusAreaChannelInfo:
+				_channelInfo, cmd := m.channelInfo.Update(msg)
+				m.channelInfo = _channelInfo.(*channelinfo.Model)
+				cmds = append(cmds, cmd)
+
+			case focusAreaProfile:
+				_profile, cmd := m.profile.Update(msg)
+				m.profile = _profile.(*profile.Model)
+				cmds = append(cmds, cmd)
 			}
 		}
 
 	default:
-		_header, cmd := m.header.Update(msg)
-		m.header = _header.(*header.Model)
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, m.broadcast(msg))
+	}
+
+	if m.focus != focusAreaActivity {
+		m.activity.Hide()
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// broadcast passes msg to every child model.
+func (m *AppModel) broadcast(msg tea.Msg) tea.Cmd {
+	cmds := make([]tea.Cmd, 0, 9)
+
+	_header, cmd := m.header.Update(msg)
+	m.header = _header.(*header.Model)
+	cmds = append(cmds, cmd)
+
+	_sidebar, cmd := m.channelTree.Update(msg)
+	m.channelTree = _sidebar.(*channeltree.Model)
+	cmds = append(cmds, cmd)
+
+	_messageInput, cmd := m.messageInput.Update(msg)
+	m.messageInput = _messageInput.(*messageinput.Model)
+	cmds = append(cmds, cmd)
+
+	_channelContent, cmd := m.channelContent.Update(msg)
+	m.channelContent = _channelContent.(*channelcontent.Model)
+	cmds = append(cmds, cmd)
+
+	_search, cmd := m.search.Update(msg)
+	m.search = _search.(*search.Model)
+	cmds = append(cmds, cmd)
+
+	_activity, cmd := m.activity.Update(msg)
+	m.activity = _activity.(*activity.Model)
+	cmds = append(cmds, cmd)
 
-		_sidebar, cmd := m.channelTree.Update(msg)
-		m.channelTree = _sidebar.(*channeltree.Model)
-		cmds = append(cmds, cmd)
+	_clips, cmd := m.clips.Update(msg)
+	m.clips = _clips.(*clips.Model)
+	cmds = append(cmds, cmd)
 
-		_messageInput, cmd := m.messageInput.Update(msg)
-		m.messageInput = _messageInput.(*messageinput.Model)
-		cmds = append(cmds, cmd)
+	_channelInfo, cmd := m.channelInfo.Update(msg)
+	m.channelInfo = _channelInfo.(*channelinfo.Model)
+	cmds = append(cmds, cmd)
 
-		_channelContent, cmd := m.channelContent.Update(msg)
-		m.channelContent = _channelContent.(*channelcontent.Model)
-		cmds = append(cmds, cmd)
+	_profile, cmd := m.profile.Update(msg)
+	m.profile = _profile.(*profile.Model)
+	cmds = append(cmds, cmd)
+
+	return tea.Batch(cmds...)
+}
+
+// findChannel looks up a public channel in the cache. Other channels, e.g. DMs,
+// are returned with their ID only.
+func (m *AppModel) findChannel(channelID uuid.UUID) *traqapi.Channel {
+	if channel, ok := m.traqContext.ChannelByID(channelID); ok {
+		return &channel
 	}
 
-	return m, tea.Batch(cmds...)
+	return &traqapi.Channel{ID: channelID}
+}
+
+func (m *AppModel) waitForRevalidationCmd() tea.Cmd {
+	return func() tea.Msg {
+		r, ok := <-m.traqContext.Revalidations()
+		if !ok {
+			return nil
+		}
+
+		return shared.CacheRevalidatedMsg{
+			Bucket: r.Bucket,
+			Key:    r.Key,
+		}
+	}
+}
+
+func (m *AppModel) waitForTerminalSequenceCmd() tea.Cmd {
+	return func() tea.Msg {
+		sequence, ok := <-m.terminal.Sequences()
+		if !ok {
+			return nil
+		}
+
+		return terminalSequenceMsg{sequence: sequence}
+	}
+}
+
+func (m *AppModel) waitForOutboxUpdateCmd() tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-m.traqContext.Outbox.Updates(); !ok {
+			return nil
+		}
+
+		return shared.OutboxUpdatedMsg{
+			ChannelIDs: m.traqContext.Outbox.TakeUpdated(),
+		}
+	}
 }
 
 func (m *AppModel) View() string {
+	// the sequences have no width, so they are written with the last line
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.theme.WithBorder(m.header.View(), m.focus == focusAreaHeader),
@@ -216,9 +549,31 @@ func (m *AppModel) View() string {
 			m.theme.WithBorder(m.channelTree.View(), m.focus == focusAreaSidebar),
 			lipgloss.JoinVertical(
 				lipgloss.Left,
-				m.theme.WithBorder(m.channelContent.View(), m.focus == focusAreaChannelContent),
+				m.mainView(),
 				m.theme.WithBorder(m.messageInput.View(), m.focus == focusAreaMessageInput),
 			),
 		),
-	)
+	) + m.sequences
+}
+
+// mainView renders the channel content, or another pane shown in its place while it is focused.
+func (m *AppModel) mainView() string {
+	switch m.focus {
+	case focusAreaSearch:
+		return m.theme.WithBorder(m.search.View(), true)
+
+	case focusAreaActivity:
+		return m.theme.WithBorder(m.activity.View(), true)
+
+	case focusAreaClips:
+		return m.theme.WithBorder(m.clips.View(), true)
+
+	case focusAreaChannelInfo:
+		return m.theme.WithBorder(m.channelInfo.View(), true)
+
+	case focusAreaProfile:
+		return m.theme.WithBorder(m.profile.View(), true)
+	}
+
+	return m.theme.WithBorder(m.channelContent.View(), m.focus == focusAreaChannelContent)
 }
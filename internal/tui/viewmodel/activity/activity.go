@@ -0,0 +1,283 @@
+package activity
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+// refreshInterval is how often the timeline is refetched while it is open.
+// traQ pushes new messages only over its WebSocket, which lazytraq does not use yet.
+const refreshInterval = 30 * time.Second
+
+const help = "j/k: select · enter: open · a: all channels · c: per channel · r: refresh · esc: back"
+
+type (
+	timelineFetchedMsg struct {
+		query    traqapiext.ActivityQuery
+		messages []traqapi.ActivityTimelineMessage
+		err      error
+	}
+	refreshTickMsg struct {
+		seq int
+	}
+)
+
+type State struct {
+	query    traqapiext.ActivityQuery
+	messages []traqapi.ActivityTimelineMessage
+	selected int
+	loading  bool
+	err      string
+	// open is true while the pane is shown, and refreshes stop when it is not.
+	open bool
+	// refreshSeq identifies the latest refresh tick, so that reopening does not
+	// start a second one.
+	refreshSeq int
+}
+
+type Model struct {
+	w, h        int
+	traqContext *traqapiext.Context
+	theme       shared.Theme
+
+	state State
+}
+
+var _ tea.Model = (*Model)(nil)
+
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
+	return &Model{
+		w:           w,
+		h:           h,
+		traqContext: traqContext,
+		theme:       theme,
+	}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+}
+
+func (m *Model) Init() tea.Cmd {
+	return nil
+}
+
+// Open shows the timeline and keeps it fresh until Hide is called.
+func (m *Model) Open() tea.Cmd {
+	m.state.open = true
+
+	return tea.Batch(m.fetchTimelineCmd(context.Background()), m.refreshTickCmd())
+}
+
+// Hide stops refreshing the timeline.
+func (m *Model) Hide() {
+	m.state.open = false
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+
+	case timelineFetchedMsg:
+		if msg.query != m.state.query {
+			return m, nil
+		}
+
+		m.state.loading = false
+		if msg.err != nil {
+			m.state.err = fmt.Sprintf("failed to load activity: %v", msg.err)
+			return m, nil
+		}
+
+		m.state.err = ""
+		m.state.messages = msg.messages
+		m.state.selected = min(m.state.selected, max(len(msg.messages)-1, 0))
+
+	case refreshTickMsg:
+		if msg.seq != m.state.refreshSeq || !m.state.open {
+			return m, nil
+		}
+
+		// the cache would serve the stale timeline while refreshing it in the background
+		m.traqContext.Activity.Forget(m.state.query)
+		return m, tea.Batch(m.fetchTimelineCmd(context.Background()), m.refreshTickCmd())
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		return func() tea.Msg {
+			return shared.ReturnToSidebarMsg{}
+		}
+
+	case "j", "down":
+		m.state.selected = min(m.state.selected+1, max(len(m.state.messages)-1, 0))
+
+	case "k", "up":
+		m.state.selected = max(m.state.selected-1, 0)
+
+	case "g", "home":
+		m.state.selected = 0
+
+	case "a":
+		m.state.query.All = !m.state.query.All
+		m.state.selected = 0
+		return m.fetchTimelineCmd(context.Background())
+
+	case "c":
+		m.state.query.PerChannel = !m.state.query.PerChannel
+		m.state.selected = 0
+		return m.fetchTimelineCmd(context.Background())
+
+	case "r":
+		m.traqContext.Activity.Forget(m.state.query)
+		return m.fetchTimelineCmd(context.Background())
+
+	case "enter":
+		if len(m.state.messages) == 0 {
+			return nil
+		}
+
+		message := m.state.messages[m.state.selected]
+		return func() tea.Msg {
+			return shared.JumpToMessageMsg{
+				ChannelID: message.GetChannelId(),
+				MessageID: message.GetID(),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Model) View() string {
+	lines := []string{m.titleView(), m.statusView()}
+
+	// each entry takes a line for its channel, author and time and one for its snippet
+	visible := max((m.h-3)/2, 1)
+	start := max(m.state.selected-visible+1, 0)
+	for i := start; i < min(start+visible, len(m.state.messages)); i++ {
+		lines = append(lines, m.entryView(m.state.messages[i], i == m.state.selected)...)
+	}
+
+	body := lipgloss.NewStyle().
+		Height(m.h - 1).
+		MaxHeight(m.h - 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.NewStyle().
+		Width(m.w).
+		Height(m.h).
+		Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			body,
+			m.theme.Activity.Meta.Render(ansi.Truncate(help, m.w, "…")),
+		))
+}
+
+func (m *Model) titleView() string {
+	scope := "subscribed channels"
+	if m.state.query.All {
+		scope = "all channels"
+	}
+
+	mode := "all messages"
+	if m.state.query.PerChannel {
+		mode = "latest per channel"
+	}
+
+	return m.theme.Activity.Title.Render("Activity") +
+		m.theme.Activity.Meta.Render(fmt.Sprintf(" · %s · %s", scope, mode))
+}
+
+func (m *Model) statusView() string {
+	switch {
+	case m.state.err != "":
+		return m.theme.Activity.Error.Render(m.state.err)
+
+	case m.state.loading && len(m.state.messages) == 0:
+		return m.theme.Activity.Meta.Render("loading…")
+
+	case len(m.state.messages) == 0:
+		return m.theme.Activity.Meta.Render("no activity")
+
+	default:
+		return ""
+	}
+}
+
+func (m *Model) entryView(message traqapi.ActivityTimelineMessage, selected bool) []string {
+	channelPath, _ := m.traqContext.ChannelPath(message.GetChannelId())
+	username := "unknown"
+	if user, ok := m.traqContext.UserByID(message.GetUserId()); ok {
+		username = user.GetName()
+	}
+
+	meta := fmt.Sprintf(
+		"#%s · @%s · %s",
+		cmp.Or(channelPath, "(private)"),
+		username,
+		formatTime(message.GetCreatedAt()),
+	)
+
+	md := m.traqContext.ParseMarkdown(message.GetContent(), traqapiext.MarkdownOptions{})
+	snippet := strings.Join(strings.Fields(md.Content), " ")
+
+	marker, style := "  ", m.theme.Activity.Entry
+	if selected {
+		marker, style = "> ", m.theme.Activity.SelectedEntry
+	}
+
+	return []string{
+		ansi.Truncate(marker+m.theme.Activity.Meta.Render(meta), m.w, "…"),
+		ansi.Truncate("  "+style.Render(snippet), m.w, "…"),
+	}
+}
+
+// formatTime shows the time for today's messages and the date for older ones.
+func formatTime(t time.Time) string {
+	t = t.Local()
+	now := time.Now()
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+
+	return t.Format("01/02 15:04")
+}
+
+func (m *Model) fetchTimelineCmd(ctx context.Context) tea.Cmd {
+	m.state.loading = true
+	query := m.state.query
+
+	return func() tea.Msg {
+		messages, err := m.traqContext.Activity.Get(ctx, query)
+		return timelineFetchedMsg{
+			query:    query,
+			messages: messages,
+			err:      err,
+		}
+	}
+}
+
+func (m *Model) refreshTickCmd() tea.Cmd {
+	m.state.refreshSeq++
+	seq := m.state.refreshSeq
+
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg {
+		return refreshTickMsg{seq: seq}
+	})
+}
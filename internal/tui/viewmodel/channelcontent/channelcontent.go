@@ -3,9 +3,12 @@ package channelcontent
 import (
 	"context"
 	"fmt"
+	"image"
+	"log/slog"
 	"slices"
+	"strings"
 
-	"github.com/blacktop/go-termimg"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
@@ -14,16 +17,95 @@ import (
 	"github.com/ras0q/lazytraq/internal/traqapi"
 	"github.com/ras0q/lazytraq/internal/traqapiext"
 	"github.com/ras0q/lazytraq/internal/tui/shared"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	// maxConcurrentStampFetches bounds the number of stamp images fetched at once.
+	maxConcurrentStampFetches = 8
+	// jumpContextSize is the number of messages shown before and after a message
+	// jumped to that is not in the Messages cache.
+	jumpContextSize = 25
 )
 
 type (
-	messagesFetchedMsg []traqapi.Message
-	usersFetchedMsg    map[uuid.UUID]traqapi.User
+	messagesFetchedMsg struct {
+		seq       int
+		channelID uuid.UUID
+		messages  []traqapi.Message
+		// jumpTo is the message to select and scroll to, or uuid.Nil.
+		jumpTo uuid.UUID
+	}
+	usersFetchedMsg      map[uuid.UUID]traqapi.User
+	stampsFetchedMsg     map[uuid.UUID]traqapi.StampWithThumbnail
+	stampImageFetchedMsg struct {
+		stampID uuid.UUID
+		img     image.Image
+	}
+	ogpFetchedMsg struct {
+		url string
+		// ogp is nil if the page has no preview
+		ogp *traqapi.Ogp
+	}
+	ogpImageFetchedMsg struct {
+		url string
+		// img is nil if the image is unavailable
+		img image.Image
+	}
+	citationFetchedMsg struct {
+		messageID uuid.UUID
+		// message is nil if the message is unavailable
+		message *traqapi.Message
+	}
 )
 
 type State struct {
+	channelID uuid.UUID
+	// messages holds the fetched messages followed by the queued ones, oldest first.
 	messages []traqapi.Message
-	users    map[uuid.UUID]traqapi.User
+	fetched  []traqapi.Message
+	// outbox holds the messages queued for sending in the channel.
+	outbox         []traqapiext.OutboxMessage
+	renderCache    map[uuid.UUID]renderedMessage
+	users          map[uuid.UUID]traqapi.User
+	stamps         map[uuid.UUID]traqapi.StampWithThumbnail
+	stampImages    map[uuid.UUID]image.Image
+	revealSpoilers bool
+	// requestedStamps holds stamps whose images are being fetched or failed to be fetched.
+	requestedStamps map[uuid.UUID]struct{}
+	// citations holds messages linked from messages, or nil if unavailable.
+	citations          map[uuid.UUID]*traqapi.Message
+	requestedCitations map[uuid.UUID]struct{}
+	// ogps holds link previews by URL, or nil if unavailable.
+	ogps          map[string]*traqapi.Ogp
+	requestedOgps map[string]struct{}
+	// ogpImages holds preview thumbnails by URL, or nil if unavailable.
+	ogpImages                map[string]image.Image
+	requestedOgpImages       map[string]struct{}
+	previewsDisabled         bool
+	previewsDisabledChannels map[uuid.UUID]struct{}
+	// selectedID is the message selected to be edited or deleted, or uuid.Nil.
+	selectedID       uuid.UUID
+	confirmingDelete bool
+	// notice replaces the last line of the view, e.g. to ask for confirmation.
+	notice string
+	// finding is true while the find query is typed.
+	finding   bool
+	findQuery string
+	// findMatches holds the messages matching findQuery, oldest first.
+	findMatches []uuid.UUID
+	// pinsOpen is true while the pinned messages are shown in place of the messages.
+	pinsOpen     bool
+	pins         []traqapi.Pin
+	pinsSelected int
+	pinsErr      string
+	// clipFolder is the clip folder shown in place of a channel, or nil.
+	clipFolder *traqapi.ClipFolder
+	// fetchSeq numbers the requests for messages of channels and clip folders.
+	fetchSeq int
+	// shownSeq is the request whose channel or clip folder is shown. Responses to
+	// older requests do not replace it, e.g. a channel opened before a clip folder.
+	shownSeq int
 }
 
 type Model struct {
@@ -32,6 +114,8 @@ type Model struct {
 	viewport    viewport.Model
 	renderer    *glamour.TermRenderer
 	theme       shared.Theme
+	stampSem    *semaphore.Weighted
+	findInput   textinput.Model
 
 	state State
 }
@@ -39,84 +123,486 @@ type Model struct {
 var _ tea.Model = (*Model)(nil)
 
 func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
-	renderer, _ := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(w-10),
-	)
-
 	vp := viewport.New(w, h)
 	vp.SetContent("No messages yet.")
 
+	findInput := textinput.New()
+	findInput.Prompt = "/"
+
 	return &Model{
 		w:           w,
 		h:           h,
 		traqContext: traqContext,
 		viewport:    vp,
-		renderer:    renderer,
+		renderer:    shared.NewMarkdownRenderer(w),
 		theme:       theme,
+		stampSem:    semaphore.NewWeighted(maxConcurrentStampFetches),
+		findInput:   findInput,
+		state: State{
+			renderCache:              make(map[uuid.UUID]renderedMessage),
+			stampImages:              make(map[uuid.UUID]image.Image),
+			requestedStamps:          make(map[uuid.UUID]struct{}),
+			citations:                make(map[uuid.UUID]*traqapi.Message),
+			requestedCitations:       make(map[uuid.UUID]struct{}),
+			ogps:                     make(map[string]*traqapi.Ogp),
+			requestedOgps:            make(map[string]struct{}),
+			ogpImages:                make(map[string]image.Image),
+			requestedOgpImages:       make(map[string]struct{}),
+			previewsDisabledChannels: make(map[uuid.UUID]struct{}),
+		},
+	}
+}
+
+// SetSize resizes the model. Cached renders for another width are re-rendered lazily.
+func (m *Model) SetSize(w, h int) tea.Cmd {
+	if w == m.w && h == m.h {
+		return nil
 	}
+
+	if w != m.w {
+		m.renderer = shared.NewMarkdownRenderer(w)
+	}
+
+	m.w, m.h = w, h
+	m.viewport.Width, m.viewport.Height = w, h
+
+	return m.refreshViewport(m.viewport.AtBottom())
 }
 
 func (m *Model) Init() tea.Cmd {
 	ctx := context.Background()
 
-	return m.fetchUsersCmd(ctx)
+	return tea.Batch(
+		m.fetchUsersCmd(ctx),
+		m.fetchStampsCmd(ctx),
+	)
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds := make([]tea.Cmd, 0, 10)
 
+	if _, ok := msg.(tea.KeyMsg); !ok && m.state.finding {
+		// e.g. cursor blinks
+		var cmd tea.Cmd
+		m.findInput, cmd = m.findInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
 	switch msg := msg.(type) {
 	case messagesFetchedMsg:
-		m.state.messages = msg
-		slices.Reverse(m.state.messages)
-		if err := m.renderMessages(); err != nil {
-			cmds = append(cmds, func() tea.Msg {
-				return shared.ErrorMsg(fmt.Errorf("render messages: %w", err))
-			})
+		m.traqContext.Outbox.Acknowledge(msg.messages)
+		if msg.channelID != m.state.channelID || m.state.clipFolder != nil {
+			if msg.seq < m.state.shownSeq {
+				break
+			}
+
+			m.state.shownSeq = msg.seq
+			m.resetView()
+		}
+		m.state.channelID = msg.channelID
+		m.state.clipFolder = nil
+		m.state.fetched = slices.Clone(msg.messages)
+		slices.Reverse(m.state.fetched)
+		m.state.outbox = m.traqContext.Outbox.Messages(msg.channelID)
+		m.updateMessages()
+		if msg.jumpTo != uuid.Nil {
+			m.state.selectedID = msg.jumpTo
+		}
+		if m.selectedIndex() < 0 {
+			m.state.selectedID = uuid.Nil
+		}
+		if msg.jumpTo != uuid.Nil && m.state.selectedID != uuid.Nil {
+			// lay out the new messages first, so that the viewport can scroll through them
+			cmds = append(cmds, m.refreshViewport(false))
+			m.scrollToSelected()
+		}
+		cmds = append(cmds, m.refreshViewport(m.state.selectedID == uuid.Nil))
+
+	case clipsFetchedMsg:
+		if msg.folder.GetID() != m.clipFolderID() {
+			if msg.seq < m.state.shownSeq {
+				break
+			}
+
+			m.state.shownSeq = msg.seq
+			m.resetView()
+		}
+		m.state.channelID = uuid.Nil
+		m.state.clipFolder = &msg.folder
+		m.state.fetched = slices.Clone(msg.messages)
+		slices.Reverse(m.state.fetched)
+		m.state.outbox = nil
+		m.updateMessages()
+		if m.selectedIndex() < 0 {
+			m.state.selectedID = uuid.Nil
+		}
+		cmds = append(cmds, m.refreshViewport(m.state.selectedID == uuid.Nil))
+
+	case unclippedMsg:
+		if msg.err != nil {
+			m.state.notice = unclipFailedNotice(msg.err)
+			break
+		}
+
+		if msg.folder.GetID() == m.clipFolderID() {
+			cmds = append(cmds, m.ShowClipFolderCmd(context.Background(), msg.folder))
+		}
+
+	case shared.MessageEditedMsg:
+		if msg.ChannelID == m.state.channelID {
+			cmds = append(cmds, m.FetchMessagesCmd(context.Background(), msg.ChannelID))
+		}
+
+	case pinsFetchedMsg:
+		if msg.channelID != m.state.channelID {
+			break
+		}
+
+		m.state.pinsErr = ""
+		if msg.err != nil {
+			m.state.pinsErr = fmt.Sprintf("failed to load pins: %v", msg.err)
+			break
 		}
 
+		m.state.pins = msg.pins
+		m.state.pinsSelected = min(m.state.pinsSelected, max(len(msg.pins)-1, 0))
+
+	case pinToggledMsg:
+		if msg.err != nil {
+			m.state.notice = pinFailedNotice(msg.err)
+			break
+		}
+
+		if msg.channelID != m.state.channelID {
+			break
+		}
+
+		ctx := context.Background()
+		cmds = append(cmds, m.FetchMessagesCmd(ctx, msg.channelID))
+		if m.state.pinsOpen {
+			cmds = append(cmds, m.fetchPinsCmd(ctx, msg.channelID))
+		}
+
+	case messageDeletedMsg:
+		if msg.err != nil {
+			m.state.notice = deleteFailedNotice(msg.err)
+			break
+		}
+
+		if msg.channelID == m.state.channelID {
+			cmds = append(cmds, m.FetchMessagesCmd(context.Background(), msg.channelID))
+		}
+
+	case shared.OutboxUpdatedMsg:
+		if !slices.Contains(msg.ChannelIDs, m.state.channelID) {
+			break
+		}
+
+		outbox := m.traqContext.Outbox.Messages(m.state.channelID)
+		if hasNewlySent(m.state.outbox, outbox) {
+			cmds = append(cmds, m.FetchMessagesCmd(context.Background(), m.state.channelID))
+		}
+
+		// follow newly queued messages
+		toBottom := len(outbox) > len(m.state.outbox) || m.viewport.AtBottom()
+		m.state.outbox = outbox
+		m.updateMessages()
+		cmds = append(cmds, m.refreshViewport(toBottom))
+
 	case usersFetchedMsg:
 		m.state.users = msg
+		clear(m.state.renderCache)
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case stampsFetchedMsg:
+		m.state.stamps = msg
+		clear(m.state.renderCache)
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case shared.PresenceUpdatedMsg:
+		// only the messages of users who came or went are rendered again
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case stampImageFetchedMsg:
+		m.state.stampImages[msg.stampID] = msg.img
+		m.invalidateMessagesWithStamp(msg.stampID)
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case ogpFetchedMsg:
+		m.state.ogps[msg.url] = msg.ogp
+		m.invalidateMessagesLinking(func(rawURL string) bool {
+			return rawURL == msg.url
+		})
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case ogpImageFetchedMsg:
+		m.state.ogpImages[msg.url] = msg.img
+		m.invalidateMessagesLinking(func(rawURL string) bool {
+			ogp := m.state.ogps[rawURL]
+			return ogp != nil && len(ogp.GetImages()) > 0 && ogp.GetImages()[0].GetURL() == msg.url
+		})
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case citationFetchedMsg:
+		m.state.citations[msg.messageID] = msg.message
+		m.invalidateMessagesCiting(msg.messageID)
+		cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+	case shared.CacheRevalidatedMsg:
+		ctx := context.Background()
+		switch msg.Bucket {
+		case traqapiext.BucketUsers:
+			cmds = append(cmds, m.fetchUsersCmd(ctx))
+
+		case traqapiext.BucketMessages:
+			if msg.Key == m.state.channelID.String() {
+				cmds = append(cmds, m.FetchMessagesCmd(ctx, m.state.channelID))
+			}
+		}
 
 	case tea.KeyMsg:
+		if m.state.confirmingDelete {
+			return m, m.handleDeleteConfirmation(msg)
+		}
+		if m.state.finding {
+			return m, m.handleFindKey(msg)
+		}
+		m.state.notice = ""
+		if m.state.pinsOpen {
+			return m, m.handlePinsKey(msg)
+		}
+
 		switch msg.String() {
 		case "esc":
+			if m.state.findQuery != "" {
+				cmds = append(cmds, m.clearFind())
+				break
+			}
+
+			if m.state.selectedID != uuid.Nil {
+				cmds = append(cmds, m.clearSelection())
+				break
+			}
+
+			if m.state.clipFolder != nil {
+				cmds = append(cmds, func() tea.Msg {
+					return shared.OpenClipsMsg{}
+				})
+				break
+			}
+
 			cmds = append(cmds, func() tea.Msg {
 				return shared.ReturnToSidebarMsg{}
 			})
+
+		case "enter":
+			if m.state.clipFolder != nil {
+				cmds = append(cmds, m.openSelectedClipCmd())
+			}
+
+		case "c":
+			cmds = append(cmds, m.handleClipKey())
+
+		case "i":
+			if channelID := m.state.channelID; channelID != uuid.Nil {
+				cmds = append(cmds, func() tea.Msg {
+					return shared.OpenChannelInfoMsg{ChannelID: channelID}
+				})
+			}
+
+		case "u":
+			if userIDs := m.selectedProfileUsers(); len(userIDs) > 0 {
+				cmds = append(cmds, func() tea.Msg {
+					return shared.OpenProfileMsg{UserIDs: userIDs}
+				})
+			}
+
+		case "/":
+			cmds = append(cmds, m.startFind())
+
+		case "ctrl+n":
+			cmds = append(cmds, m.moveFind(1))
+
+		case "ctrl+p":
+			cmds = append(cmds, m.moveFind(-1))
+
+		case "K":
+			cmds = append(cmds, m.moveSelection(-1))
+
+		case "J":
+			cmds = append(cmds, m.moveSelection(1))
+
+		case "m":
+			if i := m.selectedIndex(); i >= 0 {
+				if _, queued := m.queuedMessage(m.state.selectedID); !queued {
+					cmds = append(cmds, m.togglePinCmd(context.Background(), m.state.messages[i]))
+				}
+			}
+
+		case "M":
+			if m.state.clipFolder == nil {
+				cmds = append(cmds, m.openPins())
+			}
+
+		case "D":
+			if _, ok := m.ownSelectedMessage(); ok {
+				m.state.confirmingDelete = true
+				m.state.notice = deletePrompt
+			}
+
+		case "s":
+			m.state.revealSpoilers = !m.state.revealSpoilers
+			cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+		case "p":
+			if _, ok := m.state.previewsDisabledChannels[m.state.channelID]; ok {
+				delete(m.state.previewsDisabledChannels, m.state.channelID)
+			} else {
+				m.state.previewsDisabledChannels[m.state.channelID] = struct{}{}
+			}
+			cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+		case "P":
+			m.state.previewsDisabled = !m.state.previewsDisabled
+			cmds = append(cmds, m.refreshViewport(m.viewport.AtBottom()))
+
+		case "r":
+			for _, queued := range m.state.outbox {
+				m.traqContext.Outbox.Retry(queued.Nonce)
+			}
+
+		case "e":
+			cmds = append(cmds, m.editSelectedCmd())
 		}
 	}
 
+	yOffset := m.viewport.YOffset
 	var cmd tea.Cmd
 	m.viewport, cmd = m.viewport.Update(msg)
 	cmds = append(cmds, cmd)
 
+	if m.viewport.YOffset != yOffset {
+		cmds = append(cmds, m.refreshViewport(false))
+	}
+
 	return m, tea.Batch(cmds...)
 }
 
 func (m *Model) View() string {
+	view := m.viewport.View()
+	if m.state.pinsOpen {
+		view = m.pinsView()
+	}
+
+	var status string
+	switch {
+	case m.state.notice != "":
+		status = m.theme.ChannelContent.Failed.Render(m.state.notice)
+	case m.state.finding || m.state.findQuery != "":
+		status = m.findStatus()
+	case m.state.clipFolder != nil:
+		status = m.clipsStatus()
+	}
+
+	if status != "" {
+		lines := strings.Split(view, "\n")
+		lines[len(lines)-1] = status
+		view = strings.Join(lines, "\n")
+	}
+
 	return lipgloss.NewStyle().
 		Width(m.w).
 		Height(m.h).
-		Render(m.viewport.View())
+		Render(view)
+}
+
+func (m *Model) nextFetchSeq() int {
+	m.state.fetchSeq++
+	return m.state.fetchSeq
+}
+
+// resetView clears the state tied to the channel or clip folder shown.
+func (m *Model) resetView() {
+	m.state.selectedID = uuid.Nil
+	m.state.confirmingDelete = false
+	m.state.notice = ""
+	m.state.finding = false
+	m.state.findQuery = ""
+	m.findInput.Blur()
+	m.state.pinsOpen = false
+	m.state.pins = nil
 }
 
 func (m *Model) FetchMessagesCmd(ctx context.Context, channelID uuid.UUID) tea.Cmd {
+	seq := m.nextFetchSeq()
+
 	return func() tea.Msg {
 		messages, err := m.traqContext.Messages.Get(ctx, channelID)
 		if err != nil {
+			if traqapiext.IsUnreachable(err) {
+				return messagesFetchedMsg{seq: seq, channelID: channelID}
+			}
+
 			return shared.ErrorMsg(fmt.Errorf("get messages from traQ: %w", err))
 		}
 
-		return messagesFetchedMsg(messages)
+		return messagesFetchedMsg{
+			seq:       seq,
+			channelID: channelID,
+			messages:  messages,
+		}
 	}
 }
 
+// JumpToMessageCmd opens the channel with the message selected. Messages too old to be
+// in the Messages cache are shown with the messages around them.
+func (m *Model) JumpToMessageCmd(ctx context.Context, channelID, messageID uuid.UUID) tea.Cmd {
+	seq := m.nextFetchSeq()
+
+	return func() tea.Msg {
+		messages, err := m.traqContext.Messages.Get(ctx, channelID)
+		if err != nil && !traqapiext.IsUnreachable(err) {
+			return shared.ErrorMsg(fmt.Errorf("get messages from traQ: %w", err))
+		}
+
+		hasMessage := slices.ContainsFunc(messages, func(message traqapi.Message) bool {
+			return message.GetID() == messageID
+		})
+		if !hasMessage {
+			around, err := m.messagesAround(ctx, channelID, messageID)
+			if err != nil {
+				slog.WarnContext(ctx, "load messages around", "messageID", messageID, "error", err)
+			} else {
+				messages = around
+			}
+		}
+
+		return messagesFetchedMsg{
+			seq:       seq,
+			channelID: channelID,
+			messages:  messages,
+			jumpTo:    messageID,
+		}
+	}
+}
+
+func (m *Model) messagesAround(ctx context.Context, channelID, messageID uuid.UUID) ([]traqapi.Message, error) {
+	message, err := m.traqContext.CitedMessages.Get(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("get message: %w", err)
+	}
+
+	return m.traqContext.MessagesAround(ctx, channelID, message.GetCreatedAt(), jumpContextSize)
+}
+
 func (m *Model) fetchUsersCmd(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
 		users, err := m.traqContext.Users.Get(ctx, struct{}{})
 		if err != nil {
+			if traqapiext.IsUnreachable(err) {
+				return nil
+			}
+
 			return shared.ErrorMsg(fmt.Errorf("get users from traQ: %w", err))
 		}
 
@@ -129,86 +615,89 @@ func (m *Model) fetchUsersCmd(ctx context.Context) tea.Cmd {
 	}
 }
 
-func (m *Model) renderMessages() error {
-	if len(m.state.messages) == 0 {
-		m.viewport.SetContent("No messages yet.")
-		return nil
-	}
+func (m *Model) fetchStampsCmd(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		stamps, err := m.traqContext.Stamps.Get(ctx, struct{}{})
+		if err != nil {
+			if traqapiext.IsUnreachable(err) {
+				return nil
+			}
 
-	renderedMessages := make([]string, 0, len(m.state.messages))
-	for _, message := range m.state.messages {
-		user := m.state.users[message.GetUserId()]
-		username := traqapiext.GetUsernameOrUnknown(&user)
-		timestamp := message.GetCreatedAt().Format("15:04")
+			return shared.ErrorMsg(fmt.Errorf("get stamps from traQ: %w", err))
+		}
 
-		renderedContent, err := m.renderer.Render(message.GetContent())
-		if err != nil {
-			renderedContent = message.GetContent()
+		stampMap := make(map[uuid.UUID]traqapi.StampWithThumbnail, len(stamps))
+		for _, stamp := range stamps {
+			stampMap[stamp.ID] = stamp
 		}
 
-		var renderedStamps string
-		stamps := message.GetStamps()
-		if len(stamps) > 0 {
-			slices.SortStableFunc(stamps, func(a, b traqapi.MessageStamp) int {
-				return a.GetCreatedAt().Compare(b.GetCreatedAt())
-			})
+		return stampsFetchedMsg(stampMap)
+	}
+}
 
-			termProtocol := termimg.Halfblocks
-			stampWidth, stampHeight, stampSpacing := 10, 4, 1
-			columns := (m.w * 2 / 3) / (stampWidth/2 + stampSpacing)
-			gallery := termimg.NewImageGallery(columns)
-			stampMap := make(map[uuid.UUID]struct{})
-			for _, stamp := range stamps {
-				stampID := stamp.GetStampId()
-				if _, ok := stampMap[stampID]; ok {
-					continue
-				}
+// fetchStampImageCmd loads a stamp image in the background.
+// At most maxConcurrentStampFetches images are fetched at the same time.
+func (m *Model) fetchStampImageCmd(ctx context.Context, stampID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		if err := m.stampSem.Acquire(ctx, 1); err != nil {
+			return nil
+		}
+		defer m.stampSem.Release(1)
 
-				stampMap[stampID] = struct{}{}
+		img, err := m.traqContext.StampImages.Get(ctx, stampID)
+		if err != nil {
+			slog.WarnContext(ctx, "load stamp image", "stampID", stampID, "error", err)
+			return nil
+		}
 
-				img, err := m.traqContext.StampImages.Get(context.Background(), stampID)
-				if err != nil {
-					return fmt.Errorf("load stamp image: %w", err)
-				}
+		return stampImageFetchedMsg{
+			stampID: stampID,
+			img:     img,
+		}
+	}
+}
 
-				termImg := termimg.New(img)
-				gallery.AddImage(termImg)
-			}
+func (m *Model) fetchCitationCmd(ctx context.Context, messageID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		message, err := m.traqContext.CitedMessages.Get(ctx, messageID)
+		if err != nil {
+			slog.WarnContext(ctx, "load cited message", "messageID", messageID, "error", err)
+			return citationFetchedMsg{messageID: messageID}
+		}
 
-			gallery.
-				SetProtocol(termProtocol).
-				SetImageSize(stampWidth, stampHeight).
-				SetSpacing(stampSpacing)
-			renderedStamps, err = gallery.Render()
-			if err != nil {
-				return fmt.Errorf("render stamp gallery: %w", err)
-			}
+		return citationFetchedMsg{
+			messageID: messageID,
+			message:   message,
 		}
+	}
+}
 
-		renderedMessages = append(renderedMessages, lipgloss.JoinHorizontal(
-			lipgloss.Top,
-			m.theme.ChannelContent.Time.Render(timestamp),
-			m.theme.ChannelContent.MessageBox.
-				Render(
-					lipgloss.JoinVertical(
-						lipgloss.Left,
-						m.theme.ChannelContent.Username.Render("@"+username),
-						renderedContent,
-						"",
-						renderedStamps,
-					),
-				),
-		))
+func (m *Model) fetchOgpCmd(ctx context.Context, rawURL string) tea.Cmd {
+	return func() tea.Msg {
+		ogp, err := m.traqContext.Ogps.Get(ctx, rawURL)
+		if err != nil {
+			slog.WarnContext(ctx, "load ogp", "url", rawURL, "error", err)
+			return ogpFetchedMsg{url: rawURL}
+		}
 
+		return ogpFetchedMsg{
+			url: rawURL,
+			ogp: ogp,
+		}
 	}
+}
 
-	m.viewport.SetContent(
-		lipgloss.JoinVertical(
-			lipgloss.Left,
-			renderedMessages...,
-		),
-	)
-	m.viewport.GotoBottom()
+func (m *Model) fetchOgpImageCmd(ctx context.Context, imageURL string) tea.Cmd {
+	return func() tea.Msg {
+		img, err := m.traqContext.OgpImages.Get(ctx, imageURL)
+		if err != nil {
+			slog.WarnContext(ctx, "load ogp image", "url", imageURL, "error", err)
+			return ogpImageFetchedMsg{url: imageURL}
+		}
 
-	return nil
+		return ogpImageFetchedMsg{
+			url: imageURL,
+			img: img,
+		}
+	}
 }
@@ -0,0 +1,140 @@
+package channelcontent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const (
+	clipIcon  = "📎"
+	clipsHelp = "K/J: select · enter: open in channel · c: unclip · esc: folders"
+)
+
+type (
+	clipsFetchedMsg struct {
+		seq    int
+		folder traqapi.ClipFolder
+		// messages holds the clipped messages, latest clip first.
+		messages []traqapi.Message
+	}
+	unclippedMsg struct {
+		folder traqapi.ClipFolder
+		err    error
+	}
+)
+
+// ShowClipFolderCmd shows the messages clipped in the folder in place of a channel,
+// the latest clip at the bottom.
+func (m *Model) ShowClipFolderCmd(ctx context.Context, folder traqapi.ClipFolder) tea.Cmd {
+	seq := m.nextFetchSeq()
+
+	return func() tea.Msg {
+		clips, err := m.traqContext.Clips.Get(ctx, folder.GetID())
+		if err != nil {
+			if traqapiext.IsUnreachable(err) {
+				return clipsFetchedMsg{seq: seq, folder: folder}
+			}
+
+			return shared.ErrorMsg(fmt.Errorf("get clips from traQ: %w", err))
+		}
+
+		messages := make([]traqapi.Message, 0, len(clips))
+		for _, clip := range clips {
+			messages = append(messages, clip.GetMessage())
+		}
+
+		return clipsFetchedMsg{
+			seq:      seq,
+			folder:   folder,
+			messages: messages,
+		}
+	}
+}
+
+// clipFolderID returns the folder shown, or uuid.Nil while a channel is shown.
+func (m *Model) clipFolderID() uuid.UUID {
+	if m.state.clipFolder == nil {
+		return uuid.Nil
+	}
+
+	return m.state.clipFolder.GetID()
+}
+
+// handleClipKey clips the selected message into a folder chosen in the clips pane,
+// or removes it from the folder shown.
+func (m *Model) handleClipKey() tea.Cmd {
+	i := m.selectedIndex()
+	if i < 0 {
+		return nil
+	}
+
+	if _, queued := m.queuedMessage(m.state.selectedID); queued {
+		return nil
+	}
+
+	message := m.state.messages[i]
+	if m.state.clipFolder == nil {
+		return func() tea.Msg {
+			return shared.ClipMessageMsg{MessageID: message.GetID()}
+		}
+	}
+
+	return m.unclipCmd(context.Background(), *m.state.clipFolder, message.GetID())
+}
+
+func (m *Model) unclipCmd(ctx context.Context, folder traqapi.ClipFolder, messageID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		err := m.traqContext.UnclipMessage(ctx, folder.GetID(), messageID)
+		if err != nil {
+			slog.WarnContext(ctx, "unclip message", "folderID", folder.GetID(), "messageID", messageID, "error", err)
+		}
+
+		return unclippedMsg{
+			folder: folder,
+			err:    err,
+		}
+	}
+}
+
+// openSelectedClipCmd opens the channel of the selected clipped message.
+func (m *Model) openSelectedClipCmd() tea.Cmd {
+	i := m.selectedIndex()
+	if i < 0 {
+		return nil
+	}
+
+	message := m.state.messages[i]
+
+	return func() tea.Msg {
+		return shared.JumpToMessageMsg{
+			ChannelID: message.GetChannelId(),
+			MessageID: message.GetID(),
+		}
+	}
+}
+
+func unclipFailedNotice(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: cannot unclip messages"
+	}
+
+	return fmt.Sprintf("failed to unclip: %v", err)
+}
+
+// clipsStatus names the folder shown at the bottom of the view.
+func (m *Model) clipsStatus() string {
+	return m.theme.ChannelContent.Pending.Render(ansi.Truncate(
+		fmt.Sprintf("%s %s (%d) · %s", clipIcon, m.state.clipFolder.GetName(), len(m.state.messages), clipsHelp),
+		m.w,
+		"…",
+	))
+}
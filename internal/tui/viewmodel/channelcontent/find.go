@@ -0,0 +1,198 @@
+package channelcontent
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+)
+
+// findHelp orders the matches from the top as the messages are shown. The matches are
+// not moved with n as in vim, since n composes a message.
+const findHelp = "ctrl+n: next (newer) · ctrl+p: previous (older) · esc: clear"
+
+// startFind opens the find input at the bottom of the view.
+func (m *Model) startFind() tea.Cmd {
+	m.state.finding = true
+	m.findInput.SetValue(m.state.findQuery)
+	m.findInput.CursorEnd()
+
+	return m.findInput.Focus()
+}
+
+// handleFindKey updates the query as it is typed and selects the latest match,
+// which is the nearest one as the view starts at the bottom.
+func (m *Model) handleFindKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		return m.clearFind()
+
+	case "enter":
+		m.state.finding = false
+		m.findInput.Blur()
+		if m.state.findQuery == "" {
+			return m.clearFind()
+		}
+
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.findInput, cmd = m.findInput.Update(msg)
+	if m.findInput.Value() == m.state.findQuery {
+		return cmd
+	}
+
+	m.state.findQuery = m.findInput.Value()
+	m.updateFindMatches()
+	if len(m.state.findMatches) > 0 {
+		m.state.selectedID = m.state.findMatches[len(m.state.findMatches)-1]
+		m.scrollToSelected()
+	}
+
+	return tea.Batch(cmd, m.refreshViewport(false))
+}
+
+// moveFind selects the match delta matches after the selected one, wrapping around.
+func (m *Model) moveFind(delta int) tea.Cmd {
+	matches := m.state.findMatches
+	if len(matches) == 0 {
+		return nil
+	}
+
+	i := slices.Index(matches, m.state.selectedID)
+	if i < 0 {
+		i = len(matches) - 1
+	} else {
+		i = (i + delta + len(matches)) % len(matches)
+	}
+
+	m.state.selectedID = matches[i]
+	m.scrollToSelected()
+
+	return m.refreshViewport(false)
+}
+
+func (m *Model) clearFind() tea.Cmd {
+	m.state.finding = false
+	m.state.findQuery = ""
+	m.state.findMatches = nil
+	m.findInput.Blur()
+
+	return m.refreshViewport(false)
+}
+
+// updateFindMatches finds the messages matching the query, oldest first.
+func (m *Model) updateFindMatches() {
+	m.state.findMatches = m.state.findMatches[:0]
+	if m.state.findQuery == "" {
+		return
+	}
+
+	for _, message := range m.state.messages {
+		if m.matchesFind(message) {
+			m.state.findMatches = append(m.state.findMatches, message.GetID())
+		}
+	}
+}
+
+// matchesFind reports whether the raw content or the author of the message contains
+// the query, ignoring case.
+func (m *Model) matchesFind(message traqapi.Message) bool {
+	if m.state.findQuery == "" {
+		return false
+	}
+
+	item := traqapiext.MessageItem{
+		Message: message,
+		User:    m.state.users[message.GetUserId()],
+	}
+
+	return strings.Contains(strings.ToLower(item.FilterValue()), strings.ToLower(m.state.findQuery))
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// highlightFind highlights the query in rendered text, leaving escape sequences intact
+// and restoring the style set before a match after it. Matches broken up by styles are
+// not highlighted.
+func (m *Model) highlightFind(rendered, query string) string {
+	if query == "" {
+		return rendered
+	}
+
+	// active holds the SGR sequences since the last reset
+	active := ""
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	highlight := func(s string) string {
+		return m.theme.ChannelContent.Highlight.Render(s) + active
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range ansiPattern.FindAllStringIndex(rendered, -1) {
+		b.WriteString(pattern.ReplaceAllStringFunc(rendered[last:loc[0]], highlight))
+
+		sequence := rendered[loc[0]:loc[1]]
+		b.WriteString(sequence)
+		switch {
+		case sequence == "\x1b[0m" || sequence == "\x1b[m":
+			active = ""
+		case strings.HasSuffix(sequence, "m"):
+			active += sequence
+		}
+
+		last = loc[1]
+	}
+	b.WriteString(pattern.ReplaceAllStringFunc(rendered[last:], highlight))
+
+	return b.String()
+}
+
+// findStatus describes the find at the bottom of the view.
+func (m *Model) findStatus() string {
+	if m.state.finding {
+		return m.findInput.View()
+	}
+
+	if len(m.state.findMatches) == 0 {
+		return m.theme.ChannelContent.Failed.Render(fmt.Sprintf("/%s: no matches · esc: clear", m.state.findQuery))
+	}
+
+	current := slices.Index(m.state.findMatches, m.state.selectedID) + 1
+	return m.theme.ChannelContent.Pending.Render(fmt.Sprintf(
+		"/%s [%d/%d] · %s",
+		m.state.findQuery,
+		current,
+		len(m.state.findMatches),
+		findHelp,
+	))
+}
+
+// CapturesKey reports whether the key is used by channelcontent even though it is
+// bound globally, e.g. n while finding.
+func (m *Model) CapturesKey(key string) bool {
+	switch {
+	case m.state.finding:
+		return key != "ctrl+c"
+
+	case m.state.confirmingDelete:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func (m *Model) findQueryFor(messageID uuid.UUID) string {
+	if !slices.Contains(m.state.findMatches, messageID) {
+		return ""
+	}
+
+	return m.state.findQuery
+}
@@ -0,0 +1,116 @@
+package channelcontent
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+// updateMessages lays out the fetched messages followed by the queued ones,
+// which are shown optimistically until they are fetched.
+func (m *Model) updateMessages() {
+	var me uuid.UUID
+	if myUser, ok := m.traqContext.Me.GetIfExists(struct{}{}); ok && myUser != nil {
+		me = myUser.GetID()
+	}
+
+	messages := make([]traqapi.Message, 0, len(m.state.fetched)+len(m.state.outbox))
+	messages = append(messages, m.state.fetched...)
+	for _, queued := range m.state.outbox {
+		messages = append(messages, traqapi.Message{
+			// the nonce never collides with message IDs, so it keys the render cache
+			ID:        queued.Nonce,
+			UserId:    me,
+			ChannelId: queued.ChannelID,
+			Content:   queued.Content,
+			CreatedAt: queued.QueuedAt,
+			UpdatedAt: queued.UpdatedAt,
+		})
+	}
+
+	m.state.messages = messages
+	m.updateFindMatches()
+}
+
+// queuedMessage returns the outbox entry shown as the message, if it is one.
+func (m *Model) queuedMessage(messageID uuid.UUID) (traqapiext.OutboxMessage, bool) {
+	i := slices.IndexFunc(m.state.outbox, func(queued traqapiext.OutboxMessage) bool {
+		return queued.Nonce == messageID
+	})
+	if i < 0 {
+		return traqapiext.OutboxMessage{}, false
+	}
+
+	return m.state.outbox[i], true
+}
+
+// renderOutboxStatus renders the delivery state of a queued message.
+func (m *Model) renderOutboxStatus(queued traqapiext.OutboxMessage) string {
+	switch queued.Status {
+	case traqapiext.OutboxSending:
+		return m.theme.ChannelContent.Pending.Render("sending…")
+
+	case traqapiext.OutboxWaiting:
+		return m.theme.ChannelContent.Pending.Render(
+			fmt.Sprintf("offline, retrying… (attempt %d)", queued.Attempts),
+		)
+
+	case traqapiext.OutboxSent:
+		return m.theme.ChannelContent.Pending.Render("sent")
+
+	case traqapiext.OutboxFailed:
+		return m.theme.ChannelContent.Failed.Render(
+			fmt.Sprintf("failed to send: %v · r: retry · e: edit", queued.Err),
+		)
+
+	default:
+		return ""
+	}
+}
+
+// editFailedCmd takes the latest message that failed to be sent out of the outbox
+// and puts it back into the message input.
+func (m *Model) editFailedCmd() tea.Cmd {
+	for _, queued := range slices.Backward(m.state.outbox) {
+		if queued.Status != traqapiext.OutboxFailed {
+			continue
+		}
+
+		removed, ok := m.traqContext.Outbox.Remove(queued.Nonce)
+		if !ok {
+			continue
+		}
+
+		return func() tea.Msg {
+			return shared.RestoreDraftMsg{
+				ChannelID: removed.ChannelID,
+				Content:   removed.Content,
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasNewlySent reports whether a message in after has been accepted by traQ since before.
+func hasNewlySent(before, after []traqapiext.OutboxMessage) bool {
+	for _, queued := range after {
+		if queued.Status != traqapiext.OutboxSent {
+			continue
+		}
+
+		wasSent := slices.ContainsFunc(before, func(old traqapiext.OutboxMessage) bool {
+			return old.Nonce == queued.Nonce && old.Status == traqapiext.OutboxSent
+		})
+		if !wasSent {
+			return true
+		}
+	}
+
+	return false
+}
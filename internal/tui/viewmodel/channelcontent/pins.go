@@ -0,0 +1,178 @@
+package channelcontent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+)
+
+const (
+	pinIcon  = "📌"
+	pinsHelp = "j/k: select · enter: jump · m: unpin · esc: close"
+)
+
+type (
+	pinsFetchedMsg struct {
+		channelID uuid.UUID
+		pins      []traqapi.Pin
+		err       error
+	}
+	pinToggledMsg struct {
+		channelID uuid.UUID
+		err       error
+	}
+)
+
+// openPins shows the pinned messages of the channel in place of the messages.
+func (m *Model) openPins() tea.Cmd {
+	m.state.pinsOpen = true
+	m.state.pinsSelected = 0
+
+	return m.fetchPinsCmd(context.Background(), m.state.channelID)
+}
+
+func (m *Model) handlePinsKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "M":
+		m.state.pinsOpen = false
+
+	case "j", "down":
+		m.state.pinsSelected = min(m.state.pinsSelected+1, max(len(m.state.pins)-1, 0))
+
+	case "k", "up":
+		m.state.pinsSelected = max(m.state.pinsSelected-1, 0)
+
+	case "enter":
+		if len(m.state.pins) == 0 {
+			return nil
+		}
+
+		m.state.pinsOpen = false
+		message := m.state.pins[m.state.pinsSelected].GetMessage()
+
+		return m.JumpToMessageCmd(context.Background(), message.GetChannelId(), message.GetID())
+
+	case "m":
+		if len(m.state.pins) == 0 {
+			return nil
+		}
+
+		message := m.state.pins[m.state.pinsSelected].GetMessage()
+
+		return m.togglePinCmd(context.Background(), message)
+	}
+
+	return nil
+}
+
+// togglePinCmd pins the message, or unpins it if it is pinned.
+func (m *Model) togglePinCmd(ctx context.Context, message traqapi.Message) tea.Cmd {
+	channelID, messageID := message.GetChannelId(), message.GetID()
+	pinned := message.GetPinned()
+
+	return func() tea.Msg {
+		var err error
+		if pinned {
+			err = m.traqContext.RemovePin(ctx, channelID, messageID)
+		} else {
+			err = m.traqContext.CreatePin(ctx, channelID, messageID)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "toggle pin", "messageID", messageID, "pinned", pinned, "error", err)
+		}
+
+		return pinToggledMsg{
+			channelID: channelID,
+			err:       err,
+		}
+	}
+}
+
+func (m *Model) fetchPinsCmd(ctx context.Context, channelID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		pins, err := m.traqContext.Pins.Get(ctx, channelID)
+		return pinsFetchedMsg{
+			channelID: channelID,
+			pins:      pins,
+			err:       err,
+		}
+	}
+}
+
+func pinFailedNotice(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: cannot pin messages"
+	}
+
+	return fmt.Sprintf("failed to pin: %v", err)
+}
+
+// pinsView lists the pinned messages with who pinned them and when.
+func (m *Model) pinsView() string {
+	lines := []string{
+		m.theme.ChannelContent.Username.Render(fmt.Sprintf("%s Pinned messages (%d)", pinIcon, len(m.state.pins))),
+	}
+	switch {
+	case m.state.pinsErr != "":
+		lines = append(lines, m.theme.ChannelContent.Failed.Render(m.state.pinsErr))
+	case len(m.state.pins) == 0:
+		lines = append(lines, m.theme.ChannelContent.Pending.Render("no pinned messages"))
+	}
+
+	// each pin takes a line for who pinned it and one for the message
+	visible := max((m.h-2)/2, 1)
+	start := max(m.state.pinsSelected-visible+1, 0)
+	for i := start; i < min(start+visible, len(m.state.pins)); i++ {
+		pin := m.state.pins[i]
+		message := pin.GetMessage()
+		meta := fmt.Sprintf(
+			"@%s: pinned by @%s at %s",
+			m.usernameOf(message.GetUserId()),
+			m.usernameOf(pin.GetUserId()),
+			pin.GetPinnedAt().Local().Format("2006/01/02 15:04"),
+		)
+		md := m.traqContext.ParseMarkdown(message.GetContent(), traqapiext.MarkdownOptions{
+			RevealSpoilers: m.state.revealSpoilers,
+		})
+		snippet := strings.Join(strings.Fields(md.Content), " ")
+
+		marker, style := "  ", lipgloss.NewStyle()
+		if i == m.state.pinsSelected {
+			marker, style = "> ", m.theme.ChannelContent.Username
+		}
+
+		lines = append(lines,
+			ansi.Truncate(marker+m.theme.ChannelContent.Time.Render(meta), m.w, "…"),
+			ansi.Truncate("  "+style.Render(snippet), m.w, "…"),
+		)
+	}
+
+	body := lipgloss.NewStyle().
+		Height(m.h - 1).
+		MaxHeight(m.h - 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		body,
+		m.theme.ChannelContent.Pending.Render(ansi.Truncate(pinsHelp, m.w, "…")),
+	)
+}
+
+// usernameOf looks up users outside the loaded messages too, as pins can be old.
+func (m *Model) usernameOf(userID uuid.UUID) string {
+	if user, ok := m.traqContext.UserByID(userID); ok {
+		return user.GetName()
+	}
+
+	return "unknown"
+}
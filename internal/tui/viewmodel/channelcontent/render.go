@@ -0,0 +1,565 @@
+package channelcontent
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blacktop/go-termimg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const (
+	// renderMargin is the number of screens above and below the viewport that are rendered ahead.
+	renderMargin = 1
+	// maxRenderCacheSize bounds the number of rendered messages kept across channels.
+	maxRenderCacheSize = 2000
+)
+
+// renderKey identifies the inputs a rendered message depends on.
+type renderKey struct {
+	updatedAt time.Time
+	width     int
+	theme     string
+	// stamps is needed because adding a stamp does not change updatedAt.
+	stamps         uint64
+	revealSpoilers bool
+	previews       bool
+	selected       bool
+	// pinned is needed because pinning does not change updatedAt.
+	pinned bool
+	// online is whether the author is online, shown next to the name.
+	online bool
+	// find is the query highlighted in the message.
+	find string
+}
+
+// renderDeps lists the data a rendered message is still waiting for.
+type renderDeps struct {
+	stamps    []uuid.UUID
+	citations []uuid.UUID
+	ogps      []string
+	ogpImages []string
+}
+
+type renderedMessage struct {
+	key     renderKey
+	content string
+	height  int
+}
+
+func (m *Model) renderKeyOf(message traqapi.Message) renderKey {
+	return renderKey{
+		updatedAt:      message.GetUpdatedAt(),
+		width:          m.w,
+		theme:          m.theme.Name,
+		stamps:         stampsHash(message.GetStamps()),
+		revealSpoilers: m.state.revealSpoilers,
+		previews:       m.previewsEnabled(message.GetChannelId()),
+		selected:       message.GetID() == m.state.selectedID,
+		pinned:         message.GetPinned(),
+		online:         m.traqContext.IsOnline(message.GetUserId()),
+		find:           m.findQueryFor(message.GetID()),
+	}
+}
+
+// stampsHash identifies who added which stamps how many times, as stamping again
+// or replacing a stamp can leave the number of stamps unchanged.
+func stampsHash(stamps []traqapi.MessageStamp) uint64 {
+	h := fnv.New64a()
+	for _, stamp := range stamps {
+		stampID, userID := stamp.GetStampId(), stamp.GetUserId()
+		h.Write(stampID[:])
+		h.Write(userID[:])
+		binary.Write(h, binary.LittleEndian, stamp.GetCount())
+	}
+
+	return h.Sum64()
+}
+
+func (m *Model) cachedRender(message traqapi.Message) (renderedMessage, bool) {
+	r, ok := m.state.renderCache[message.GetID()]
+	if !ok || r.key != m.renderKeyOf(message) {
+		return renderedMessage{}, false
+	}
+
+	return r, true
+}
+
+// render renders a message and stores it in the render cache.
+// It returns the data that is not loaded yet.
+func (m *Model) render(message traqapi.Message) (renderedMessage, renderDeps) {
+	content, deps := m.renderMessage(message)
+	r := renderedMessage{
+		key:     m.renderKeyOf(message),
+		content: content,
+		height:  lipgloss.Height(content),
+	}
+
+	if len(m.state.renderCache) >= maxRenderCacheSize {
+		clear(m.state.renderCache)
+	}
+	m.state.renderCache[message.GetID()] = r
+
+	return r, deps
+}
+
+// estimateHeight guesses the height of a message that has not been rendered yet.
+func (m *Model) estimateHeight(message traqapi.Message) int {
+	width := max(m.w-10, 1)
+	lines := 0
+	for line := range strings.SplitSeq(message.GetContent(), "\n") {
+		lines += max(lipgloss.Width(line)-1, 0)/width + 1
+	}
+
+	// username, blank line before stamps and glamour margins
+	height := lines + 4
+	if len(message.GetStamps()) > 0 {
+		height += 4
+	}
+
+	return height
+}
+
+// refreshViewport lays out the messages in the viewport. Only messages within
+// renderMargin screens of the viewport are rendered; the others are replaced
+// with blank lines of their estimated height. It returns a command that fetches
+// the stamp images and cited messages missing in the rendered messages.
+func (m *Model) refreshViewport(toBottom bool) tea.Cmd {
+	if len(m.state.messages) == 0 {
+		m.viewport.SetContent("No messages yet.")
+		return nil
+	}
+
+	heights := make([]int, len(m.state.messages))
+	for i, message := range m.state.messages {
+		if r, ok := m.cachedRender(message); ok {
+			heights[i] = r.height
+		} else {
+			heights[i] = m.estimateHeight(message)
+		}
+	}
+
+	yOffset := m.viewport.YOffset
+	var missing renderDeps
+	for range 3 {
+		if toBottom {
+			yOffset = max(sum(heights)-m.viewport.Height, 0)
+		}
+
+		top := yOffset - m.viewport.Height*renderMargin
+		bottom := yOffset + m.viewport.Height*(1+renderMargin)
+
+		rendered := false
+		offset := 0
+		for i, message := range m.state.messages {
+			height := heights[i]
+			if offset+height > top && offset < bottom {
+				if _, ok := m.cachedRender(message); !ok {
+					r, deps := m.render(message)
+					missing.stamps = append(missing.stamps, deps.stamps...)
+					missing.citations = append(missing.citations, deps.citations...)
+					missing.ogps = append(missing.ogps, deps.ogps...)
+					missing.ogpImages = append(missing.ogpImages, deps.ogpImages...)
+					heights[i] = r.height
+					rendered = true
+
+					// keep the content in the viewport still
+					if !toBottom && offset < yOffset {
+						yOffset += r.height - height
+					}
+				}
+			}
+
+			offset += heights[i]
+		}
+
+		if !rendered {
+			break
+		}
+	}
+
+	parts := make([]string, 0, len(m.state.messages))
+	for i, message := range m.state.messages {
+		if r, ok := m.cachedRender(message); ok {
+			parts = append(parts, r.content)
+		} else {
+			parts = append(parts, strings.Repeat("\n", heights[i]-1))
+		}
+	}
+
+	m.viewport.SetContent(strings.Join(parts, "\n"))
+	m.viewport.SetYOffset(yOffset)
+	if toBottom {
+		m.viewport.GotoBottom()
+	}
+
+	return m.fetchMissingCmd(missing)
+}
+
+func (m *Model) fetchMissingCmd(missing renderDeps) tea.Cmd {
+	ctx := context.Background()
+	cmds := make([]tea.Cmd, 0, len(missing.stamps)+len(missing.citations)+len(missing.ogps)+len(missing.ogpImages))
+	for _, stampID := range missing.stamps {
+		if _, ok := m.state.requestedStamps[stampID]; ok {
+			continue
+		}
+
+		m.state.requestedStamps[stampID] = struct{}{}
+		cmds = append(cmds, m.fetchStampImageCmd(ctx, stampID))
+	}
+
+	for _, messageID := range missing.citations {
+		if _, ok := m.state.requestedCitations[messageID]; ok {
+			continue
+		}
+
+		m.state.requestedCitations[messageID] = struct{}{}
+		cmds = append(cmds, m.fetchCitationCmd(ctx, messageID))
+	}
+
+	for _, rawURL := range missing.ogps {
+		if _, ok := m.state.requestedOgps[rawURL]; ok {
+			continue
+		}
+
+		m.state.requestedOgps[rawURL] = struct{}{}
+		cmds = append(cmds, m.fetchOgpCmd(ctx, rawURL))
+	}
+
+	for _, imageURL := range missing.ogpImages {
+		if _, ok := m.state.requestedOgpImages[imageURL]; ok {
+			continue
+		}
+
+		m.state.requestedOgpImages[imageURL] = struct{}{}
+		cmds = append(cmds, m.fetchOgpImageCmd(ctx, imageURL))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// invalidateMessagesWithStamp drops the cached renders of the messages that have the stamp.
+func (m *Model) invalidateMessagesWithStamp(stampID uuid.UUID) {
+	for _, message := range m.state.messages {
+		hasStamp := slices.ContainsFunc(message.GetStamps(), func(s traqapi.MessageStamp) bool {
+			return s.GetStampId() == stampID
+		})
+		if hasStamp {
+			delete(m.state.renderCache, message.GetID())
+		}
+	}
+}
+
+// invalidateMessagesCiting drops the cached renders of the messages that link to messageID.
+func (m *Model) invalidateMessagesCiting(messageID uuid.UUID) {
+	for _, message := range m.state.messages {
+		if strings.Contains(message.GetContent(), messageID.String()) {
+			delete(m.state.renderCache, message.GetID())
+		}
+	}
+}
+
+// invalidateMessagesLinking drops the cached renders of the messages that have
+// a link preview for which match returns true.
+func (m *Model) invalidateMessagesLinking(match func(rawURL string) bool) {
+	for _, message := range m.state.messages {
+		if slices.ContainsFunc(m.traqContext.FindPreviewURLs(message.GetContent()), match) {
+			delete(m.state.renderCache, message.GetID())
+		}
+	}
+}
+
+// renderMessage renders a message. Stamps whose images are not loaded yet are
+// rendered as :name:, and cited messages not loaded yet as a placeholder;
+// both are returned as missing.
+func (m *Model) renderMessage(message traqapi.Message) (string, renderDeps) {
+	user := m.state.users[message.GetUserId()]
+	username := traqapiext.GetUsernameOrUnknown(&user)
+	timestamp := message.GetCreatedAt().Format("15:04")
+
+	findQuery := m.findQueryFor(message.GetID())
+	renderedContent := m.highlightFind(m.renderContent(message.GetContent()), findQuery)
+	renderedCitations, missingCitations := m.renderCitations(message.GetContent())
+	renderedPreviews, previewDeps := m.renderPreviews(message)
+	renderedStamps, missingStamps := m.renderStamps(message.GetStamps())
+
+	var renderedStatus string
+	if queued, ok := m.queuedMessage(message.GetID()); ok {
+		renderedStatus = m.renderOutboxStatus(queued)
+	}
+
+	messageBox := m.theme.ChannelContent.MessageBox
+	if message.GetID() == m.state.selectedID {
+		messageBox = m.theme.ChannelContent.SelectedMessageBox
+	}
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		m.theme.ChannelContent.Time.Render(timestamp),
+		messageBox.
+			Render(
+				lipgloss.JoinVertical(
+					lipgloss.Left,
+					m.renderUsername(username, m.traqContext.IsOnline(message.GetUserId()), message.GetPinned(), findQuery),
+					renderedContent,
+					renderedCitations,
+					renderedPreviews,
+					renderedStatus,
+					"",
+					renderedStamps,
+				),
+			),
+	), renderDeps{
+		stamps:    missingStamps,
+		citations: missingCitations,
+		ogps:      previewDeps.ogps,
+		ogpImages: previewDeps.ogpImages,
+	}
+}
+
+// renderUsername renders the author of a message after their presence, marked if
+// the message is pinned.
+func (m *Model) renderUsername(username string, online, pinned bool, findQuery string) string {
+	rendered := m.theme.PresenceDot(online) + " " +
+		m.highlightFind(m.theme.ChannelContent.Username.Render("@"+username), findQuery)
+	if pinned {
+		rendered += " " + pinIcon
+	}
+
+	return rendered
+}
+
+// maxPreviewsPerMessage bounds the number of link previews rendered for a message.
+const maxPreviewsPerMessage = 3
+
+// renderPreviews renders OGP cards for the external URLs in the message.
+func (m *Model) renderPreviews(message traqapi.Message) (string, renderDeps) {
+	var missing renderDeps
+	if !m.previewsEnabled(message.GetChannelId()) {
+		return "", missing
+	}
+
+	urls := m.traqContext.FindPreviewURLs(message.GetContent())
+	if len(urls) > maxPreviewsPerMessage {
+		urls = urls[:maxPreviewsPerMessage]
+	}
+
+	cardWidth := max(min(m.w-14, 72), 20)
+	thumbnailWidth, thumbnailHeight := 16, 6
+	cards := make([]string, 0, len(urls))
+	for _, rawURL := range urls {
+		ogp, ok := m.state.ogps[rawURL]
+		if !ok {
+			missing.ogps = append(missing.ogps, rawURL)
+			continue
+		}
+
+		if ogp == nil || (ogp.GetTitle() == "" && ogp.GetDescription() == "") {
+			continue
+		}
+
+		siteName := rawURL
+		if u, err := url.Parse(cmp.Or(ogp.GetURL(), rawURL)); err == nil {
+			siteName = u.Host
+		}
+
+		var thumbnail string
+		if images := ogp.GetImages(); len(images) > 0 {
+			imageURL := images[0].GetURL()
+			img, ok := m.state.ogpImages[imageURL]
+			if !ok {
+				missing.ogpImages = append(missing.ogpImages, imageURL)
+			} else if img != nil {
+				rendered, err := termimg.New(img).
+					Protocol(termimg.Halfblocks).
+					Size(thumbnailWidth, thumbnailHeight).
+					Render()
+				if err == nil {
+					thumbnail = rendered
+				}
+			}
+		}
+
+		textWidth := cardWidth - 2
+		if thumbnail != "" {
+			textWidth -= thumbnailWidth + 1
+		}
+
+		text := lipgloss.NewStyle().Width(textWidth).Render(
+			lipgloss.JoinVertical(
+				lipgloss.Left,
+				m.theme.ChannelContent.PreviewSite.Render(siteName),
+				m.theme.ChannelContent.PreviewTitle.Render(ogp.GetTitle()),
+				truncateLines(ogp.GetDescription(), textWidth, 2),
+			),
+		)
+
+		body := text
+		if thumbnail != "" {
+			body = lipgloss.JoinHorizontal(lipgloss.Top, thumbnail, " ", text)
+		}
+
+		cards = append(cards, m.theme.ChannelContent.Preview.Render(body))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, cards...), missing
+}
+
+func (m *Model) previewsEnabled(channelID uuid.UUID) bool {
+	if m.state.previewsDisabled {
+		return false
+	}
+
+	_, disabled := m.state.previewsDisabledChannels[channelID]
+	return !disabled
+}
+
+// truncateLines wraps s to width and keeps at most n lines.
+func truncateLines(s string, width, n int) string {
+	lines := strings.Split(lipgloss.NewStyle().Width(width).Render(s), "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+		lines[n-1] = strings.TrimRight(lines[n-1], " ") + "…"
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderCitations renders the messages linked from content as quote blocks.
+func (m *Model) renderCitations(content string) (string, []uuid.UUID) {
+	messageIDs := m.traqContext.FindMessageLinks(content)
+	if len(messageIDs) == 0 {
+		return "", nil
+	}
+
+	quoteStyle := m.theme.ChannelContent.Quote.Width(max(m.w-14, 10))
+	quotes := make([]string, 0, len(messageIDs))
+	missing := make([]uuid.UUID, 0)
+	for _, messageID := range messageIDs {
+		cited, ok := m.state.citations[messageID]
+		if !ok {
+			quotes = append(quotes, quoteStyle.Render("loading quoted message..."))
+			missing = append(missing, messageID)
+			continue
+		}
+
+		if cited == nil {
+			quotes = append(quotes, quoteStyle.Render("quoted message is unavailable"))
+			continue
+		}
+
+		user := m.state.users[cited.GetUserId()]
+		channel := "#" + cmp.Or(m.channelPath(cited.GetChannelId()), "unknown")
+		header := fmt.Sprintf(
+			"%s in %s · %s",
+			m.theme.ChannelContent.Username.Render("@"+traqapiext.GetUsernameOrUnknown(&user)),
+			channel,
+			cited.GetCreatedAt().Local().Format("2006/01/02 15:04"),
+		)
+		md := m.traqContext.ParseMarkdown(cited.GetContent(), traqapiext.MarkdownOptions{
+			RevealSpoilers: m.state.revealSpoilers,
+		})
+
+		quotes = append(quotes, quoteStyle.Render(header+"\n"+md.Content))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, quotes...), missing
+}
+
+func (m *Model) channelPath(channelID uuid.UUID) string {
+	path, _ := m.traqContext.ChannelPath(channelID)
+	return path
+}
+
+// renderContent renders the content of a message.
+func (m *Model) renderContent(content string) string {
+	return shared.RenderMarkdown(m.traqContext, m.renderer, m.theme.ChannelContent, content, traqapiext.MarkdownOptions{
+		RevealSpoilers: m.state.revealSpoilers,
+	})
+}
+
+func (m *Model) renderStamps(stamps []traqapi.MessageStamp) (string, []uuid.UUID) {
+	if len(stamps) == 0 {
+		return "", nil
+	}
+
+	stamps = slices.Clone(stamps)
+	slices.SortStableFunc(stamps, func(a, b traqapi.MessageStamp) int {
+		return a.GetCreatedAt().Compare(b.GetCreatedAt())
+	})
+
+	termProtocol := termimg.Halfblocks
+	stampWidth, stampHeight, stampSpacing := 10, 4, 1
+	columns := (m.w * 2 / 3) / (stampWidth/2 + stampSpacing)
+	gallery := termimg.NewImageGallery(columns)
+	hasImages := false
+	placeholders := make([]string, 0)
+	missingStamps := make([]uuid.UUID, 0)
+	stampMap := make(map[uuid.UUID]struct{})
+	for _, stamp := range stamps {
+		stampID := stamp.GetStampId()
+		if _, ok := stampMap[stampID]; ok {
+			continue
+		}
+
+		stampMap[stampID] = struct{}{}
+
+		img, ok := m.state.stampImages[stampID]
+		if !ok {
+			placeholders = append(placeholders, m.stampPlaceholder(stampID))
+			missingStamps = append(missingStamps, stampID)
+			continue
+		}
+
+		gallery.AddImage(termimg.New(img))
+		hasImages = true
+	}
+
+	parts := make([]string, 0, 2)
+	if hasImages {
+		gallery.
+			SetProtocol(termProtocol).
+			SetImageSize(stampWidth, stampHeight).
+			SetSpacing(stampSpacing)
+		renderedGallery, err := gallery.Render()
+		if err == nil {
+			parts = append(parts, renderedGallery)
+		}
+	}
+
+	if len(placeholders) > 0 {
+		parts = append(parts, strings.Join(placeholders, " "))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...), missingStamps
+}
+
+func (m *Model) stampPlaceholder(stampID uuid.UUID) string {
+	name := "unknown"
+	if stamp, ok := m.state.stamps[stampID]; ok {
+		name = stamp.GetName()
+	}
+
+	return fmt.Sprintf(":%s:", name)
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+
+	return total
+}
@@ -0,0 +1,208 @@
+package channelcontent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const deletePrompt = "delete this message? y: confirm, other: cancel"
+
+type messageDeletedMsg struct {
+	channelID uuid.UUID
+	messageID uuid.UUID
+	err       error
+}
+
+// moveSelection selects the message delta messages after the selected one.
+// Without a selection, the latest message is selected.
+func (m *Model) moveSelection(delta int) tea.Cmd {
+	if len(m.state.messages) == 0 {
+		return nil
+	}
+
+	i := m.selectedIndex()
+	if i < 0 {
+		i = len(m.state.messages) - 1
+	} else {
+		i = min(max(i+delta, 0), len(m.state.messages)-1)
+	}
+
+	m.state.selectedID = m.state.messages[i].GetID()
+	m.scrollToSelected()
+
+	return m.refreshViewport(false)
+}
+
+func (m *Model) clearSelection() tea.Cmd {
+	m.state.selectedID = uuid.Nil
+	m.state.confirmingDelete = false
+
+	return m.refreshViewport(m.viewport.AtBottom())
+}
+
+func (m *Model) selectedIndex() int {
+	if m.state.selectedID == uuid.Nil {
+		return -1
+	}
+
+	return slices.IndexFunc(m.state.messages, func(message traqapi.Message) bool {
+		return message.GetID() == m.state.selectedID
+	})
+}
+
+// scrollToSelected moves the viewport so that the selected message is visible.
+// Messages not rendered yet are measured by their estimated height.
+func (m *Model) scrollToSelected() {
+	i := m.selectedIndex()
+	if i < 0 {
+		return
+	}
+
+	offset := 0
+	for _, message := range m.state.messages[:i] {
+		offset += m.heightOf(message)
+	}
+
+	height := m.heightOf(m.state.messages[i])
+	switch {
+	case offset < m.viewport.YOffset || height > m.viewport.Height:
+		m.viewport.SetYOffset(offset)
+
+	case offset+height > m.viewport.YOffset+m.viewport.Height:
+		m.viewport.SetYOffset(offset + height - m.viewport.Height)
+	}
+}
+
+func (m *Model) heightOf(message traqapi.Message) int {
+	if r, ok := m.cachedRender(message); ok {
+		return r.height
+	}
+
+	return m.estimateHeight(message)
+}
+
+// ownSelectedMessage returns the selected message if it was posted by the current user
+// and has been accepted by traQ.
+func (m *Model) ownSelectedMessage() (traqapi.Message, bool) {
+	i := m.selectedIndex()
+	if i < 0 {
+		return traqapi.Message{}, false
+	}
+
+	message := m.state.messages[i]
+	if _, queued := m.queuedMessage(message.GetID()); queued {
+		return traqapi.Message{}, false
+	}
+
+	me, ok := m.traqContext.Me.GetIfExists(struct{}{})
+	if !ok || me == nil || me.GetID() != message.GetUserId() {
+		return traqapi.Message{}, false
+	}
+
+	return message, true
+}
+
+// editSelectedCmd opens the selected message in the message input.
+// A queued message that failed to be sent is taken out of the outbox instead,
+// and without a selection the latest failed one is.
+func (m *Model) editSelectedCmd() tea.Cmd {
+	if i := m.selectedIndex(); i >= 0 {
+		if queued, ok := m.queuedMessage(m.state.selectedID); ok {
+			removed, ok := m.traqContext.Outbox.Remove(queued.Nonce)
+			if !ok {
+				return nil
+			}
+
+			return func() tea.Msg {
+				return shared.RestoreDraftMsg{
+					ChannelID: removed.ChannelID,
+					Content:   removed.Content,
+				}
+			}
+		}
+
+		message, ok := m.ownSelectedMessage()
+		if !ok {
+			return nil
+		}
+
+		return func() tea.Msg {
+			return shared.EditMessageMsg{
+				ChannelID: message.GetChannelId(),
+				MessageID: message.GetID(),
+				Content:   message.GetContent(),
+			}
+		}
+	}
+
+	return m.editFailedCmd()
+}
+
+// handleDeleteConfirmation answers the delete prompt with msg.
+func (m *Model) handleDeleteConfirmation(msg tea.KeyMsg) tea.Cmd {
+	m.state.confirmingDelete = false
+	m.state.notice = ""
+	if msg.String() != "y" {
+		return nil
+	}
+
+	message, ok := m.ownSelectedMessage()
+	if !ok {
+		return nil
+	}
+
+	return m.deleteMessageCmd(context.Background(), message.GetChannelId(), message.GetID())
+}
+
+func (m *Model) deleteMessageCmd(ctx context.Context, channelID, messageID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		err := m.traqContext.DeleteMessage(ctx, channelID, messageID)
+		if err != nil {
+			slog.WarnContext(ctx, "delete message", "messageID", messageID, "error", err)
+		}
+
+		return messageDeletedMsg{
+			channelID: channelID,
+			messageID: messageID,
+			err:       err,
+		}
+	}
+}
+
+// deleteFailedNotice describes why a message could not be deleted.
+func deleteFailedNotice(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: cannot delete messages"
+	}
+
+	return fmt.Sprintf("failed to delete: %v", err)
+}
+
+// selectedProfileUsers returns the author of the selected message followed by the
+// users it mentions, without duplicates.
+func (m *Model) selectedProfileUsers() []uuid.UUID {
+	i := m.selectedIndex()
+	if i < 0 {
+		return nil
+	}
+
+	message := m.state.messages[i]
+	userIDs := []uuid.UUID{message.GetUserId()}
+	md := m.traqContext.ParseMarkdown(message.GetContent(), traqapiext.MarkdownOptions{})
+	for _, token := range md.Tokens {
+		if token.Kind == traqapiext.TokenUser && !slices.Contains(userIDs, token.ID) {
+			userIDs = append(userIDs, token.ID)
+		}
+	}
+
+	return userIDs
+}
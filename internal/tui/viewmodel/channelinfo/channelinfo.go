@@ -0,0 +1,439 @@
+package channelinfo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const (
+	help      = "j/k: scroll · t: edit topic · r: refresh · esc: back"
+	inputHelp = "enter: save · esc: cancel"
+	// maxTopStamps bounds the number of stamps listed in the stats.
+	maxTopStamps = 10
+)
+
+type (
+	topicFetchedMsg struct {
+		channelID uuid.UUID
+		topic     string
+		err       error
+	}
+	infoFetchedMsg struct {
+		channelID uuid.UUID
+		info      *traqapiext.ChannelInfo
+		err       error
+	}
+	topicEditedMsg struct {
+		channelID uuid.UUID
+		err       error
+	}
+)
+
+type State struct {
+	channelID uuid.UUID
+	topic     string
+	info      *traqapiext.ChannelInfo
+	loading   bool
+	err       string
+	// notice replaces the status line, e.g. when the topic failed to be saved.
+	notice string
+	// scroll is the number of lines scrolled past.
+	scroll int
+}
+
+type Model struct {
+	w, h        int
+	traqContext *traqapiext.Context
+	theme       shared.Theme
+	topicInput  textinput.Model
+
+	state State
+}
+
+var _ tea.Model = (*Model)(nil)
+
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
+	topicInput := textinput.New()
+	topicInput.Prompt = "topic: "
+
+	return &Model{
+		w:           w,
+		h:           h,
+		traqContext: traqContext,
+		theme:       theme,
+		topicInput:  topicInput,
+	}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+	m.topicInput.Width = w - lipgloss.Width(m.topicInput.Prompt) - 1
+}
+
+func (m *Model) Init() tea.Cmd {
+	return nil
+}
+
+// Open shows the info of the channel.
+func (m *Model) Open(channelID uuid.UUID) tea.Cmd {
+	if channelID != m.state.channelID {
+		m.state = State{channelID: channelID}
+	}
+	m.state.notice = ""
+	m.topicInput.Blur()
+
+	ctx := context.Background()
+	return tea.Batch(m.fetchTopicCmd(ctx), m.fetchInfoCmd(ctx))
+}
+
+// CapturesKey reports whether the key is used by channelinfo even though it is
+// bound globally, e.g. n in the topic.
+func (m *Model) CapturesKey(key string) bool {
+	return m.topicInput.Focused() && key != "ctrl+c"
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.topicInput.Focused() {
+			return m, m.handleInputKey(msg)
+		}
+
+		m.state.notice = ""
+		return m, m.handleKey(msg)
+
+	case topicFetchedMsg:
+		if msg.channelID != m.state.channelID {
+			return m, nil
+		}
+
+		if msg.err != nil {
+			m.state.err = fmt.Sprintf("failed to load topic: %v", msg.err)
+			return m, nil
+		}
+
+		m.state.topic = msg.topic
+
+	case infoFetchedMsg:
+		if msg.channelID != m.state.channelID {
+			return m, nil
+		}
+
+		m.state.loading = false
+		if msg.err != nil {
+			m.state.err = fmt.Sprintf("failed to load channel info: %v", msg.err)
+			return m, nil
+		}
+
+		m.state.err = ""
+		m.state.info = msg.info
+
+	case topicEditedMsg:
+		if msg.err != nil {
+			m.state.notice = topicFailedNotice(msg.err)
+			return m, nil
+		}
+
+		return m, func() tea.Msg {
+			return shared.ChannelTopicEditedMsg{ChannelID: msg.channelID}
+		}
+
+	case shared.ChannelTopicEditedMsg:
+		if msg.ChannelID == m.state.channelID {
+			return m, m.fetchTopicCmd(context.Background())
+		}
+	}
+
+	if m.topicInput.Focused() {
+		// e.g. cursor blinks
+		var cmd tea.Cmd
+		m.topicInput, cmd = m.topicInput.Update(msg)
+
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		return func() tea.Msg {
+			return shared.ReturnToChannelContentMsg{}
+		}
+
+	case "j", "down":
+		m.state.scroll = min(m.state.scroll+1, max(len(m.bodyLines())-(m.h-1), 0))
+
+	case "k", "up":
+		m.state.scroll = max(m.state.scroll-1, 0)
+
+	case "g", "home":
+		m.state.scroll = 0
+
+	case "t":
+		m.topicInput.SetValue(m.state.topic)
+		m.topicInput.CursorEnd()
+		return m.topicInput.Focus()
+
+	case "r":
+		m.traqContext.ChannelTopics.Forget(m.state.channelID)
+		m.traqContext.ChannelInfos.Forget(m.state.channelID)
+
+		ctx := context.Background()
+		return tea.Batch(m.fetchTopicCmd(ctx), m.fetchInfoCmd(ctx))
+	}
+
+	return nil
+}
+
+func (m *Model) handleInputKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.topicInput.Blur()
+		return nil
+
+	case "enter":
+		m.topicInput.Blur()
+		topic := strings.TrimSpace(m.topicInput.Value())
+		if topic == m.state.topic {
+			return nil
+		}
+
+		return m.editTopicCmd(context.Background(), topic)
+	}
+
+	var cmd tea.Cmd
+	m.topicInput, cmd = m.topicInput.Update(msg)
+
+	return cmd
+}
+
+func (m *Model) View() string {
+	lines := m.bodyLines()
+	scroll := min(m.state.scroll, max(len(lines)-(m.h-1), 0))
+	lines = lines[scroll:]
+
+	body := lipgloss.NewStyle().
+		Height(m.h - 1).
+		MaxHeight(m.h - 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.NewStyle().
+		Width(m.w).
+		Height(m.h).
+		Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			body,
+			m.footerView(),
+		))
+}
+
+// bodyLines renders the sections of the panel wrapped to its width.
+func (m *Model) bodyLines() []string {
+	title := "#" + cmp.Or(m.channelPath(), "(private)")
+	parts := []string{
+		m.theme.ChannelInfo.Title.Render(title) + m.theme.ChannelInfo.Meta.Render(" · channel info"),
+	}
+
+	switch {
+	case m.state.notice != "":
+		parts = append(parts, m.theme.ChannelInfo.Error.Render(m.state.notice))
+	case m.state.err != "":
+		parts = append(parts, m.theme.ChannelInfo.Error.Render(m.state.err))
+	}
+
+	topic := m.state.topic
+	if topic == "" {
+		topic = m.theme.ChannelInfo.Meta.Render("(no topic)")
+	}
+	parts = append(parts, m.section("Topic", topic))
+
+	info := m.state.info
+	if info == nil {
+		if m.state.loading {
+			parts = append(parts, m.theme.ChannelInfo.Meta.Render("loading…"))
+		}
+
+		return m.wrap(parts)
+	}
+
+	parts = append(parts,
+		m.section("Stats", m.statsView(info.Stats)),
+		m.section(fmt.Sprintf("Viewers (%d)", len(info.Viewers)), m.viewersView(info.Viewers)),
+		m.section(fmt.Sprintf("Subscribers (%d)", len(info.Subscribers)), m.subscribersView(info.Subscribers)),
+		m.section(fmt.Sprintf("Bots (%d)", len(info.Bots)), m.botsView(info.Bots)),
+	)
+
+	return m.wrap(parts)
+}
+
+func (m *Model) wrap(parts []string) []string {
+	rendered := lipgloss.NewStyle().Width(m.w).Render(strings.Join(parts, "\n\n"))
+	return strings.Split(rendered, "\n")
+}
+
+func (m *Model) section(heading, content string) string {
+	return m.theme.ChannelInfo.Section.Render(heading) + "\n" + content
+}
+
+func (m *Model) statsView(stats traqapi.ChannelStats) string {
+	stamps := slices.Clone(stats.GetStamps())
+	slices.SortFunc(stamps, func(a, b traqapi.ChannelStatsStamp) int {
+		return cmp.Compare(b.GetTotal(), a.GetTotal())
+	})
+
+	var totalStamps int64
+	for _, stamp := range stamps {
+		totalStamps += stamp.GetTotal()
+	}
+
+	summary := fmt.Sprintf("%d messages · %d stamps", stats.GetTotalMessageCount(), totalStamps)
+
+	top := make([]string, 0, maxTopStamps)
+	for _, stamp := range stamps[:min(len(stamps), maxTopStamps)] {
+		name := "unknown"
+		if s, ok := m.traqContext.StampByID(stamp.GetID()); ok {
+			name = s.GetName()
+		}
+
+		top = append(top, fmt.Sprintf(":%s: ×%d", name, stamp.GetTotal()))
+	}
+
+	if len(top) == 0 {
+		return summary
+	}
+
+	return summary + "\n" + m.theme.ChannelInfo.Meta.Render(strings.Join(top, "  "))
+}
+
+func (m *Model) viewersView(viewers []traqapi.ChannelViewer) string {
+	if len(viewers) == 0 {
+		return m.theme.ChannelInfo.Meta.Render("nobody is viewing")
+	}
+
+	names := make([]string, 0, len(viewers))
+	for _, viewer := range viewers {
+		name := "@" + m.username(viewer.GetUserId())
+		if state := viewer.GetState(); state != traqapi.ChannelViewStateNone {
+			name += m.theme.ChannelInfo.Meta.Render(fmt.Sprintf(" (%s)", state))
+		}
+
+		names = append(names, name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (m *Model) subscribersView(subscribers []uuid.UUID) string {
+	if subscribers == nil {
+		return m.theme.ChannelInfo.Meta.Render("not available in this channel")
+	}
+
+	if len(subscribers) == 0 {
+		return m.theme.ChannelInfo.Meta.Render("no subscribers")
+	}
+
+	names := make([]string, 0, len(subscribers))
+	for _, userID := range subscribers {
+		names = append(names, "@"+m.username(userID))
+	}
+	slices.Sort(names)
+
+	return strings.Join(names, ", ")
+}
+
+func (m *Model) botsView(bots []traqapi.BotUser) string {
+	if len(bots) == 0 {
+		return m.theme.ChannelInfo.Meta.Render("no bots joined")
+	}
+
+	names := make([]string, 0, len(bots))
+	for _, bot := range bots {
+		names = append(names, "@"+m.username(bot.GetBotUserId()))
+	}
+	slices.Sort(names)
+
+	return strings.Join(names, ", ")
+}
+
+func (m *Model) footerView() string {
+	if m.topicInput.Focused() {
+		return m.topicInput.View()
+	}
+
+	return m.theme.ChannelInfo.Meta.Render(ansi.Truncate(help, m.w, "…"))
+}
+
+func (m *Model) username(userID uuid.UUID) string {
+	if user, ok := m.traqContext.UserByID(userID); ok {
+		return user.GetName()
+	}
+
+	return "unknown"
+}
+
+func (m *Model) channelPath() string {
+	path, _ := m.traqContext.ChannelPath(m.state.channelID)
+	return path
+}
+
+func (m *Model) fetchTopicCmd(ctx context.Context) tea.Cmd {
+	channelID := m.state.channelID
+
+	return func() tea.Msg {
+		topic, err := m.traqContext.ChannelTopics.Get(ctx, channelID)
+		return topicFetchedMsg{
+			channelID: channelID,
+			topic:     topic,
+			err:       err,
+		}
+	}
+}
+
+func (m *Model) fetchInfoCmd(ctx context.Context) tea.Cmd {
+	m.state.loading = true
+	channelID := m.state.channelID
+
+	return func() tea.Msg {
+		info, err := m.traqContext.ChannelInfos.Get(ctx, channelID)
+		return infoFetchedMsg{
+			channelID: channelID,
+			info:      info,
+			err:       err,
+		}
+	}
+}
+
+func (m *Model) editTopicCmd(ctx context.Context, topic string) tea.Cmd {
+	channelID := m.state.channelID
+
+	return func() tea.Msg {
+		err := m.traqContext.EditChannelTopic(ctx, channelID, topic)
+		return topicEditedMsg{
+			channelID: channelID,
+			err:       err,
+		}
+	}
+}
+
+func topicFailedNotice(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: cannot edit the topic"
+	}
+
+	return fmt.Sprintf("failed to edit the topic: %v", err)
+}
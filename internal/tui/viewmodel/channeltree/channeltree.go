@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/uuid"
@@ -19,22 +20,37 @@ type (
 
 type State struct {
 	tree *traqapiext.ChannelNode
+	// formMode is what the form at the bottom is open for.
+	formMode formMode
+	// formTarget is the channel the form or the confirmation is for.
+	formTarget uuid.UUID
+	formErr    string
+	confirm    confirmAction
+	// notice replaces the form, e.g. to ask for confirmation.
+	notice        string
+	noticeIsError bool
+	// focusAfterFetch is the channel to focus once the channels are refetched.
+	focusAfterFetch uuid.UUID
 }
 
 type Model struct {
 	w, h        int
 	traqContext *traqapiext.Context
 	treeModel   bubbletree.Model[uuid.UUID]
+	theme       shared.Theme
+	form        textinput.Model
 
 	state State
 }
 
-func New(w, h int, traqContext *traqapiext.Context) *Model {
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
 	model := &Model{
 		w:           w,
 		h:           h,
 		traqContext: traqContext,
 		treeModel:   bubbletree.New[uuid.UUID](w, h),
+		theme:       theme,
+		form:        textinput.New(),
 	}
 	model.treeModel.OnUpdate = model.OnTreeUpdate
 	model.treeModel.Symbols = bubbletree.TreeSymbols{
@@ -50,6 +66,11 @@ func New(w, h int, traqContext *traqapiext.Context) *Model {
 
 var _ tea.Model = (*Model)(nil)
 
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+	m.treeModel.Width, m.treeModel.Height = w, h
+}
+
 func (m *Model) Init() tea.Cmd {
 	ctx := context.Background()
 	return m.fetchChannelsCmd(ctx)
@@ -62,9 +83,65 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case channelsFetchedMsg:
 		publicChannels := msg.Public
 		tree := traqapiext.ConstructTree(publicChannels)
+		if m.state.tree != nil {
+			tree.CopyOpenState(m.state.tree)
+		}
+		tree.MarkDrafts(m.draftChannelIDs())
 		m.state.tree = tree
+
+		focusID := m.state.focusAfterFetch
+		m.state.focusAfterFetch = uuid.Nil
+		m.revealChannel(focusID)
+
 		cmd := m.treeModel.SetTree(tree)
 		cmds = append(cmds, cmd)
+		if focusID != uuid.Nil {
+			cmds = append(cmds, m.treeModel.SetFocusedID(focusID))
+		}
+
+	case channelEditedMsg:
+		if msg.err != nil {
+			m.state.notice = editFailedNotice(msg.err)
+			m.state.noticeIsError = true
+			break
+		}
+
+		m.state.notice = msg.done
+		m.state.noticeIsError = false
+		m.state.focusAfterFetch = msg.channelID
+		cmds = append(cmds, m.fetchChannelsCmd(context.Background()))
+
+	case shared.DraftsChangedMsg:
+		if m.state.tree == nil {
+			break
+		}
+
+		m.state.tree.MarkDrafts(m.draftChannelIDs())
+		cmds = append(cmds, m.treeModel.SetTree(m.state.tree))
+
+	case shared.CacheRevalidatedMsg:
+		if msg.Bucket == traqapiext.BucketChannels {
+			cmds = append(cmds, m.fetchChannelsCmd(context.Background()))
+		}
+
+	case tea.KeyMsg:
+		switch {
+		case m.form.Focused():
+			return m, m.handleFormKey(msg)
+
+		case m.state.confirm != confirmNone:
+			return m, m.handleConfirmKey(msg)
+		}
+
+		m.state.notice = ""
+
+	default:
+		if m.form.Focused() {
+			// e.g. cursor blinks
+			var cmd tea.Cmd
+			m.form, cmd = m.form.Update(msg)
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	var cmd tea.Cmd
@@ -76,16 +153,45 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) View() string {
+	status := m.statusView()
+	if status == "" {
+		return lipgloss.NewStyle().
+			Width(m.w).
+			Height(m.h).
+			Render(m.treeModel.View())
+	}
+
+	treeHeight := max(m.h-lipgloss.Height(status), 0)
+	tree := lipgloss.NewStyle().
+		Width(m.w).
+		Height(treeHeight).
+		MaxHeight(treeHeight).
+		Render(m.treeModel.View())
+
 	return lipgloss.NewStyle().
 		Width(m.w).
 		Height(m.h).
-		Render(m.treeModel.View())
+		MaxHeight(m.h).
+		Render(lipgloss.JoinVertical(lipgloss.Left, tree, status))
+}
+
+func (m *Model) draftChannelIDs() map[uuid.UUID]struct{} {
+	ids := make(map[uuid.UUID]struct{})
+	for _, id := range m.traqContext.Drafts.ChannelIDs() {
+		ids[id] = struct{}{}
+	}
+
+	return ids
 }
 
 func (m *Model) fetchChannelsCmd(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
 		channels, err := m.traqContext.Channels.Get(ctx, struct{}{})
 		if err != nil {
+			if traqapiext.IsUnreachable(err) {
+				return nil
+			}
+
 			return shared.ErrorMsg(fmt.Errorf("get channels from traQ: %w", err))
 		}
 
@@ -154,6 +260,24 @@ func (m *Model) OnTreeUpdate(renderedLines []bubbletree.RenderedLine[uuid.UUID],
 				m.treeModel.SetFocusedID(focusedID),
 			)
 
+		case "a":
+			cmd = m.startForm(formModeCreate, focusedID)
+
+		case "A":
+			cmd = m.startForm(formModeCreate, uuid.Nil)
+
+		case "R":
+			cmd = m.startForm(formModeRename, focusedID)
+
+		case "M":
+			cmd = m.startForm(formModeMove, focusedID)
+
+		case "X":
+			m.startConfirm(confirmArchive, focusedID)
+
+		case "F":
+			m.startConfirm(confirmForce, focusedID)
+
 		case "enter":
 			cmd = func() tea.Msg {
 				channelNode, ok := m.state.tree.Search(focusedID)
@@ -0,0 +1,391 @@
+package channeltree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+)
+
+// formMode is what the text typed into the form is for.
+type formMode int
+
+const (
+	formModeNone formMode = iota
+	formModeCreate
+	formModeRename
+	formModeMove
+)
+
+// confirmAction is the action waiting for y to be pressed.
+type confirmAction int
+
+const (
+	confirmNone confirmAction = iota
+	confirmArchive
+	confirmForce
+)
+
+type channelEditedMsg struct {
+	// channelID is the channel to focus once the tree is updated.
+	channelID uuid.UUID
+	// done describes the change on success.
+	done string
+	err  error
+}
+
+// startForm opens the form at the bottom of the tree for the target channel,
+// or for the root if targetID is uuid.Nil.
+func (m *Model) startForm(mode formMode, targetID uuid.UUID) tea.Cmd {
+	target, ok := m.state.tree.Search(targetID)
+	if !ok {
+		return nil
+	}
+
+	var prompt, value string
+	switch mode {
+	case formModeCreate:
+		prompt = "new channel in " + m.label(target) + "/: "
+		if targetID == uuid.Nil {
+			prompt = "new channel: "
+		}
+
+	case formModeRename:
+		prompt = "rename " + m.label(target) + " to: "
+		value = target.Channel.GetName()
+
+	case formModeMove:
+		prompt = "move " + m.label(target) + " under #"
+		if parentID, ok := target.Channel.GetParentId().Get(); ok {
+			value, _ = m.traqContext.ChannelPath(parentID)
+		}
+	}
+
+	m.state.formMode = mode
+	m.state.formTarget = targetID
+	m.state.formErr = ""
+	m.form.Prompt = prompt
+	m.form.Width = m.w - lipgloss.Width(prompt) - 1
+	m.form.SetValue(value)
+	m.form.CursorEnd()
+
+	return m.form.Focus()
+}
+
+func (m *Model) handleFormKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.closeForm()
+		return nil
+
+	case "enter":
+		if err := m.validateForm(); err != nil {
+			m.state.formErr = err.Error()
+			return nil
+		}
+
+		cmd := m.submitForm(context.Background())
+		m.closeForm()
+
+		return cmd
+	}
+
+	var cmd tea.Cmd
+	m.form, cmd = m.form.Update(msg)
+
+	m.state.formErr = ""
+	if m.form.Value() != "" {
+		if err := m.validateForm(); err != nil {
+			m.state.formErr = err.Error()
+		}
+	}
+
+	return cmd
+}
+
+func (m *Model) closeForm() {
+	m.state.formMode = formModeNone
+	m.state.formErr = ""
+	m.form.Blur()
+}
+
+// validateForm checks the name against the rules of traQ and the channels in the tree,
+// so that most conflicts are found before asking traQ.
+func (m *Model) validateForm() error {
+	target, ok := m.state.tree.Search(m.state.formTarget)
+	if !ok {
+		return errors.New("the channel no longer exists")
+	}
+
+	value := strings.TrimSpace(m.form.Value())
+	switch m.state.formMode {
+	case formModeCreate:
+		if err := traqapiext.ValidateChannelName(value); err != nil {
+			return err
+		}
+
+		if target.HasChildNamed(value) {
+			return fmt.Errorf("%s/%s already exists", m.label(target), value)
+		}
+
+	case formModeRename:
+		if err := traqapiext.ValidateChannelName(value); err != nil {
+			return err
+		}
+
+		// changing the case of the name is fine
+		if !strings.EqualFold(value, target.Channel.GetName()) && m.parentOf(target).HasChildNamed(value) {
+			return fmt.Errorf("a sibling named %s already exists", value)
+		}
+
+	case formModeMove:
+		parent, err := m.lookupParent(value)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := target.Search(parent.ID()); ok {
+			return errors.New("cannot move a channel under itself")
+		}
+
+		if parent == m.parentOf(target) {
+			return errors.New("the channel is already there")
+		}
+
+		if parent.HasChildNamed(target.Channel.GetName()) {
+			return fmt.Errorf("%s already has a channel named %s", m.label(parent), target.Channel.GetName())
+		}
+	}
+
+	return nil
+}
+
+// lookupParent finds the channel at the path, or the root for an empty path.
+func (m *Model) lookupParent(path string) (*traqapiext.ChannelNode, error) {
+	path = strings.Trim(path, "#/ ")
+	if path == "" {
+		return m.state.tree, nil
+	}
+
+	channelID, ok := m.traqContext.ChannelByPath(path)
+	if !ok {
+		return nil, fmt.Errorf("unknown channel: #%s", path)
+	}
+
+	node, ok := m.state.tree.Search(channelID)
+	if !ok {
+		return nil, fmt.Errorf("unknown channel: #%s", path)
+	}
+
+	return node, nil
+}
+
+func (m *Model) submitForm(ctx context.Context) tea.Cmd {
+	target, ok := m.state.tree.Search(m.state.formTarget)
+	if !ok {
+		return nil
+	}
+
+	value := strings.TrimSpace(m.form.Value())
+	switch m.state.formMode {
+	case formModeCreate:
+		return m.createChannelCmd(ctx, value, target.ID())
+
+	case formModeRename:
+		return m.editChannelCmd(ctx, target.ID(), traqapi.PatchChannelRequest{
+			Name: traqapi.NewOptString(value),
+		}, fmt.Sprintf("renamed %s to %s", m.label(target), value))
+
+	case formModeMove:
+		parent, err := m.lookupParent(value)
+		if err != nil {
+			return nil
+		}
+
+		return m.editChannelCmd(ctx, target.ID(), traqapi.PatchChannelRequest{
+			// uuid.Nil for the root moves the channel to the root
+			Parent: traqapi.NewOptUUID(parent.ID()),
+		}, fmt.Sprintf("moved %s under %s", m.label(target), m.label(parent)))
+	}
+
+	return nil
+}
+
+// startConfirm asks to archive the channel or toggle its force flag.
+func (m *Model) startConfirm(action confirmAction, targetID uuid.UUID) {
+	target, ok := m.state.tree.Search(targetID)
+	if !ok || targetID == uuid.Nil {
+		return
+	}
+
+	question := "archive " + m.label(target) + "?"
+	if action == confirmForce {
+		question = "force notifications in " + m.label(target) + "?"
+		if target.Channel.GetForce() {
+			question = "stop forcing notifications in " + m.label(target) + "?"
+		}
+	}
+
+	m.state.confirm = action
+	m.state.formTarget = targetID
+	m.state.notice = question + " y: confirm, other: cancel"
+}
+
+func (m *Model) handleConfirmKey(msg tea.KeyMsg) tea.Cmd {
+	action := m.state.confirm
+	m.state.confirm = confirmNone
+	m.state.notice = ""
+
+	target, ok := m.state.tree.Search(m.state.formTarget)
+	if msg.String() != "y" || !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	switch action {
+	case confirmArchive:
+		return m.editChannelCmd(ctx, target.ID(), traqapi.PatchChannelRequest{
+			Archived: traqapi.NewOptBool(true),
+		}, "archived "+m.label(target))
+
+	case confirmForce:
+		force := !target.Channel.GetForce()
+		done := "forced notifications in " + m.label(target)
+		if !force {
+			done = "stopped forcing notifications in " + m.label(target)
+		}
+
+		return m.editChannelCmd(ctx, target.ID(), traqapi.PatchChannelRequest{
+			Force: traqapi.NewOptBool(force),
+		}, done)
+	}
+
+	return nil
+}
+
+func (m *Model) createChannelCmd(ctx context.Context, name string, parentID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		channel, err := m.traqContext.CreateChannel(ctx, name, parentID)
+		if err != nil {
+			return channelEditedMsg{err: err}
+		}
+
+		return channelEditedMsg{
+			channelID: channel.GetID(),
+			done:      "created #" + name,
+		}
+	}
+}
+
+func (m *Model) editChannelCmd(ctx context.Context, channelID uuid.UUID, request traqapi.PatchChannelRequest, done string) tea.Cmd {
+	focusID := channelID
+	if request.Archived.Or(false) {
+		// the archived channel is hidden, so focus its parent
+		if node, ok := m.state.tree.Search(channelID); ok {
+			focusID = m.parentOf(node).ID()
+		}
+	}
+
+	return func() tea.Msg {
+		if err := m.traqContext.EditChannel(ctx, channelID, request); err != nil {
+			return channelEditedMsg{err: err}
+		}
+
+		return channelEditedMsg{
+			channelID: focusID,
+			done:      done,
+		}
+	}
+}
+
+// revealChannel opens the ancestors of the channel so that it can be focused.
+func (m *Model) revealChannel(channelID uuid.UUID) {
+	node, ok := m.state.tree.Search(channelID)
+	for ok {
+		parentID, hasParent := node.Channel.GetParentId().Get()
+		if !hasParent {
+			return
+		}
+
+		node, ok = m.state.tree.Search(parentID)
+		if ok {
+			node.IsOpen.Store(true)
+		}
+	}
+}
+
+// parentOf returns the parent node of the node, or the root.
+func (m *Model) parentOf(node *traqapiext.ChannelNode) *traqapiext.ChannelNode {
+	if parentID, ok := node.Channel.GetParentId().Get(); ok {
+		if parent, ok := m.state.tree.Search(parentID); ok {
+			return parent
+		}
+	}
+
+	return m.state.tree
+}
+
+// label names a channel by its path, e.g. "#gps/times".
+func (m *Model) label(node *traqapiext.ChannelNode) string {
+	if node.ID() == uuid.Nil {
+		return "the root"
+	}
+
+	if path, ok := m.traqContext.ChannelPath(node.ID()); ok {
+		return "#" + path
+	}
+
+	return "#" + node.Channel.GetName()
+}
+
+// CapturesKey reports whether the key is used by channeltree even though it is
+// bound globally, e.g. n in a channel name.
+func (m *Model) CapturesKey(key string) bool {
+	switch {
+	case m.form.Focused():
+		return key != "ctrl+c"
+
+	case m.state.confirm != confirmNone:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// statusView renders the form or the notice at the bottom of the tree.
+func (m *Model) statusView() string {
+	switch {
+	case m.form.Focused() && m.state.formErr != "":
+		return m.form.View() + "\n" + m.theme.ChannelTree.Error.Render(ansi.Truncate(m.state.formErr, m.w, "…"))
+
+	case m.form.Focused():
+		return m.form.View()
+
+	case m.state.notice != "":
+		style := m.theme.ChannelTree.Notice
+		if m.state.noticeIsError {
+			style = m.theme.ChannelTree.Error
+		}
+
+		return style.Render(lipgloss.NewStyle().Width(m.w).Render(m.state.notice))
+
+	default:
+		return ""
+	}
+}
+
+func editFailedNotice(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: cannot change channels"
+	}
+
+	return fmt.Sprintf("failed: %v", err)
+}
@@ -0,0 +1,442 @@
+package clips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const (
+	help       = "j/k: select · enter: open · a: new folder · R: rename · D: delete · r: refresh · esc: back"
+	chooseHelp = "j/k: select · enter: clip into this folder · a: new folder · esc: cancel"
+	inputHelp  = "enter: save · esc: cancel"
+)
+
+type (
+	foldersFetchedMsg struct {
+		folders []traqapi.ClipFolder
+		err     error
+	}
+	// folderChangedMsg is sent when a folder has been created, renamed or deleted.
+	folderChangedMsg struct {
+		action string
+		err    error
+	}
+	messageClippedMsg struct {
+		err error
+	}
+)
+
+// inputMode is what the name typed into the input is for.
+type inputMode int
+
+const (
+	inputModeNone inputMode = iota
+	inputModeCreate
+	inputModeRename
+)
+
+type State struct {
+	folders  []traqapi.ClipFolder
+	selected int
+	loading  bool
+	err      string
+	// clipping is the message to clip into the folder chosen, or uuid.Nil while browsing.
+	clipping         uuid.UUID
+	inputMode        inputMode
+	confirmingDelete bool
+	// notice replaces the status line, e.g. to ask for confirmation.
+	notice string
+}
+
+type Model struct {
+	w, h        int
+	traqContext *traqapiext.Context
+	theme       shared.Theme
+	input       textinput.Model
+
+	state State
+}
+
+var _ tea.Model = (*Model)(nil)
+
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
+	input := textinput.New()
+	input.CharLimit = 30
+
+	return &Model{
+		w:           w,
+		h:           h,
+		traqContext: traqContext,
+		theme:       theme,
+		input:       input,
+	}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+	m.input.Width = w - lipgloss.Width(m.input.Prompt) - 1
+}
+
+func (m *Model) Init() tea.Cmd {
+	return nil
+}
+
+// Open shows the folders to browse their clips.
+func (m *Model) Open() tea.Cmd {
+	m.state.clipping = uuid.Nil
+	m.state.notice = ""
+
+	return m.fetchFoldersCmd(context.Background())
+}
+
+// ChooseFolder shows the folders to clip the message into one of them.
+func (m *Model) ChooseFolder(messageID uuid.UUID) tea.Cmd {
+	m.state.clipping = messageID
+	m.state.notice = ""
+
+	return m.fetchFoldersCmd(context.Background())
+}
+
+// CapturesKey reports whether the key is used by clips even though it is bound
+// globally, e.g. n in a folder name.
+func (m *Model) CapturesKey(key string) bool {
+	switch {
+	case m.input.Focused():
+		return key != "ctrl+c"
+
+	case m.state.confirmingDelete:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case m.input.Focused():
+			return m, m.handleInputKey(msg)
+
+		case m.state.confirmingDelete:
+			return m, m.handleDeleteConfirmation(msg)
+		}
+
+		m.state.notice = ""
+		return m, m.handleKey(msg)
+
+	case foldersFetchedMsg:
+		m.state.loading = false
+		if msg.err != nil {
+			m.state.err = fmt.Sprintf("failed to load clip folders: %v", msg.err)
+			return m, nil
+		}
+
+		m.state.err = ""
+		m.state.folders = msg.folders
+		m.state.selected = min(m.state.selected, max(len(msg.folders)-1, 0))
+
+	case folderChangedMsg:
+		if msg.err != nil {
+			m.state.notice = failedNotice(msg.action, msg.err)
+			return m, nil
+		}
+
+		return m, m.fetchFoldersCmd(context.Background())
+
+	case messageClippedMsg:
+		if msg.err != nil {
+			m.state.notice = failedNotice("clip message", msg.err)
+			return m, nil
+		}
+
+		m.state.clipping = uuid.Nil
+		return m, func() tea.Msg {
+			return shared.ReturnToChannelContentMsg{}
+		}
+	}
+
+	if m.input.Focused() {
+		// e.g. cursor blinks
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		if m.state.clipping != uuid.Nil {
+			m.state.clipping = uuid.Nil
+			return func() tea.Msg {
+				return shared.ReturnToChannelContentMsg{}
+			}
+		}
+
+		return func() tea.Msg {
+			return shared.ReturnToSidebarMsg{}
+		}
+
+	case "j", "down":
+		m.state.selected = min(m.state.selected+1, max(len(m.state.folders)-1, 0))
+
+	case "k", "up":
+		m.state.selected = max(m.state.selected-1, 0)
+
+	case "g", "home":
+		m.state.selected = 0
+
+	case "r":
+		m.traqContext.ClipFolders.Forget(struct{}{})
+		return m.fetchFoldersCmd(context.Background())
+
+	case "a":
+		return m.startInput(inputModeCreate, "new folder: ", "")
+
+	case "R":
+		if folder, ok := m.selectedFolder(); ok && m.state.clipping == uuid.Nil {
+			return m.startInput(inputModeRename, "rename: ", folder.GetName())
+		}
+
+	case "D":
+		if folder, ok := m.selectedFolder(); ok && m.state.clipping == uuid.Nil {
+			m.state.confirmingDelete = true
+			m.state.notice = fmt.Sprintf("delete folder %s? y: confirm, other: cancel", folder.GetName())
+		}
+
+	case "enter":
+		folder, ok := m.selectedFolder()
+		if !ok {
+			return nil
+		}
+
+		if m.state.clipping != uuid.Nil {
+			return m.clipCmd(context.Background(), folder.GetID(), m.state.clipping)
+		}
+
+		return func() tea.Msg {
+			return shared.OpenClipFolderMsg{Folder: folder}
+		}
+	}
+
+	return nil
+}
+
+func (m *Model) startInput(mode inputMode, prompt, value string) tea.Cmd {
+	m.state.inputMode = mode
+	m.input.Prompt = prompt
+	m.input.Width = m.w - lipgloss.Width(prompt) - 1
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+
+	return m.input.Focus()
+}
+
+func (m *Model) handleInputKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.input.Blur()
+		m.state.inputMode = inputModeNone
+		return nil
+
+	case "enter":
+		name := strings.TrimSpace(m.input.Value())
+		if name == "" {
+			return nil
+		}
+
+		mode := m.state.inputMode
+		m.input.Blur()
+		m.state.inputMode = inputModeNone
+
+		ctx := context.Background()
+		switch mode {
+		case inputModeCreate:
+			return m.createFolderCmd(ctx, name)
+
+		case inputModeRename:
+			if folder, ok := m.selectedFolder(); ok {
+				return m.renameFolderCmd(ctx, folder.GetID(), name)
+			}
+		}
+
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+
+	return cmd
+}
+
+func (m *Model) handleDeleteConfirmation(msg tea.KeyMsg) tea.Cmd {
+	m.state.confirmingDelete = false
+	m.state.notice = ""
+
+	folder, ok := m.selectedFolder()
+	if msg.String() != "y" || !ok {
+		return nil
+	}
+
+	return m.deleteFolderCmd(context.Background(), folder.GetID())
+}
+
+func (m *Model) selectedFolder() (traqapi.ClipFolder, bool) {
+	if m.state.selected >= len(m.state.folders) {
+		return traqapi.ClipFolder{}, false
+	}
+
+	return m.state.folders[m.state.selected], true
+}
+
+func (m *Model) View() string {
+	lines := []string{m.titleView(), m.statusView()}
+
+	// each folder takes a line for its name and one for its description and date
+	visible := max((m.h-3)/2, 1)
+	start := max(m.state.selected-visible+1, 0)
+	for i := start; i < min(start+visible, len(m.state.folders)); i++ {
+		lines = append(lines, m.folderView(m.state.folders[i], i == m.state.selected)...)
+	}
+
+	body := lipgloss.NewStyle().
+		Height(m.h - 1).
+		MaxHeight(m.h - 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.NewStyle().
+		Width(m.w).
+		Height(m.h).
+		Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			body,
+			m.footerView(),
+		))
+}
+
+func (m *Model) titleView() string {
+	hint := "bookmarks"
+	if m.state.clipping != uuid.Nil {
+		hint = "choose a folder to clip the message into"
+	}
+
+	return m.theme.Clips.Title.Render("Clips") + m.theme.Clips.Meta.Render(" · "+hint)
+}
+
+func (m *Model) statusView() string {
+	switch {
+	case m.state.notice != "":
+		return m.theme.Clips.Error.Render(m.state.notice)
+
+	case m.state.err != "":
+		return m.theme.Clips.Error.Render(m.state.err)
+
+	case m.state.loading && len(m.state.folders) == 0:
+		return m.theme.Clips.Meta.Render("loading…")
+
+	case len(m.state.folders) == 0:
+		return m.theme.Clips.Meta.Render("no clip folders · a: create one")
+
+	default:
+		return ""
+	}
+}
+
+func (m *Model) folderView(folder traqapi.ClipFolder, selected bool) []string {
+	meta := "created " + folder.GetCreatedAt().Local().Format("2006/01/02")
+	if description := strings.Join(strings.Fields(folder.GetDescription()), " "); description != "" {
+		meta = description + " · " + meta
+	}
+
+	marker, style := "  ", m.theme.Clips.Folder
+	if selected {
+		marker, style = "> ", m.theme.Clips.SelectedFolder
+	}
+
+	return []string{
+		ansi.Truncate(marker+style.Render(folder.GetName()), m.w, "…"),
+		ansi.Truncate("  "+m.theme.Clips.Meta.Render(meta), m.w, "…"),
+	}
+}
+
+func (m *Model) footerView() string {
+	if m.input.Focused() {
+		return m.input.View()
+	}
+
+	footer := help
+	if m.state.clipping != uuid.Nil {
+		footer = chooseHelp
+	}
+
+	return m.theme.Clips.Meta.Render(ansi.Truncate(footer, m.w, "…"))
+}
+
+func (m *Model) fetchFoldersCmd(ctx context.Context) tea.Cmd {
+	m.state.loading = true
+
+	return func() tea.Msg {
+		folders, err := m.traqContext.ClipFolders.Get(ctx, struct{}{})
+		return foldersFetchedMsg{
+			folders: folders,
+			err:     err,
+		}
+	}
+}
+
+func (m *Model) createFolderCmd(ctx context.Context, name string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := m.traqContext.CreateClipFolder(ctx, name, "")
+		return folderChangedMsg{action: "create folder", err: err}
+	}
+}
+
+func (m *Model) renameFolderCmd(ctx context.Context, folderID uuid.UUID, name string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.traqContext.RenameClipFolder(ctx, folderID, name)
+		return folderChangedMsg{action: "rename folder", err: err}
+	}
+}
+
+func (m *Model) deleteFolderCmd(ctx context.Context, folderID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		err := m.traqContext.DeleteClipFolder(ctx, folderID)
+		return folderChangedMsg{action: "delete folder", err: err}
+	}
+}
+
+func (m *Model) clipCmd(ctx context.Context, folderID, messageID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		err := m.traqContext.ClipMessage(ctx, folderID, messageID)
+		if err != nil {
+			slog.WarnContext(ctx, "clip message", "folderID", folderID, "messageID", messageID, "error", err)
+		}
+
+		return messageClippedMsg{err: err}
+	}
+}
+
+func failedNotice(action string, err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return fmt.Sprintf("offline: cannot %s", action)
+	}
+
+	return fmt.Sprintf("failed to %s: %v", action, err)
+}
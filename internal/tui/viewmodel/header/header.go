@@ -3,20 +3,40 @@ package header
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
 	"github.com/ras0q/lazytraq/internal/traqapi"
 	"github.com/ras0q/lazytraq/internal/traqapiext"
 	"github.com/ras0q/lazytraq/internal/tui/shared"
 )
 
 type (
-	meFetchedMsg *traqapi.MyUserDetail
+	meFetchedMsg       *traqapi.MyUserDetail
+	connectionCheckMsg struct{}
+	presenceTickMsg    struct{}
+	topicFetchedMsg    struct {
+		channelID uuid.UUID
+		topic     string
+	}
 )
 
+const connectionCheckInterval = 30 * time.Second
+
+// presenceRefreshInterval is how often the online users are refetched.
+// traQ pushes presence changes only over its WebSocket, which lazytraq does not use yet.
+const presenceRefreshInterval = 30 * time.Second
+
 type State struct {
 	me *traqapi.MyUserDetail
+	// channelID is the open channel, whose topic is shown.
+	channelID uuid.UUID
+	topic     string
 }
 
 type Model struct {
@@ -38,39 +58,142 @@ func New(w, h int, apiHost string, traqContext *traqapiext.Context, theme shared
 	}
 }
 
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+}
+
 func (m *Model) Init() tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-		me, err := m.traqContext.Me.Get(ctx, struct{}{})
-		if err != nil {
-			return shared.ErrorMsg(fmt.Errorf("fetch me: %w", err))
-		}
+	return tea.Batch(
+		func() tea.Msg {
+			ctx := context.Background()
+			me, err := m.traqContext.Me.Get(ctx, struct{}{})
+			if err != nil {
+				if traqapiext.IsUnreachable(err) {
+					return nil
+				}
 
-		return meFetchedMsg(me)
-	}
+				return shared.ErrorMsg(fmt.Errorf("fetch me: %w", err))
+			}
+
+			return meFetchedMsg(me)
+		},
+		m.connectionCheckTickCmd(),
+		m.fetchPresenceCmd(context.Background()),
+		m.presenceTickCmd(),
+	)
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case meFetchedMsg:
 		m.state.me = msg
+
+	case connectionCheckMsg:
+		return m, m.connectionCheckTickCmd()
+
+	case presenceTickMsg:
+		return m, tea.Batch(m.fetchPresenceCmd(context.Background()), m.presenceTickCmd())
+
+	case topicFetchedMsg:
+		if msg.channelID == m.state.channelID {
+			m.state.topic = msg.topic
+		}
+
+	case shared.ChannelTopicEditedMsg:
+		if msg.ChannelID == m.state.channelID {
+			return m, m.fetchTopicCmd(context.Background(), msg.ChannelID)
+		}
 	}
 
 	return m, nil
 }
 
+// ShowChannelCmd shows the topic of the channel opened.
+func (m *Model) ShowChannelCmd(ctx context.Context, channelID uuid.UUID) tea.Cmd {
+	if channelID == m.state.channelID {
+		return nil
+	}
+
+	m.state.channelID = channelID
+	m.state.topic = ""
+
+	return m.fetchTopicCmd(ctx, channelID)
+}
+
+func (m *Model) fetchTopicCmd(ctx context.Context, channelID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		topic, err := m.traqContext.ChannelTopics.Get(ctx, channelID)
+		if err != nil {
+			slog.WarnContext(ctx, "load channel topic", "channelID", channelID, "error", err)
+			return nil
+		}
+
+		return topicFetchedMsg{
+			channelID: channelID,
+			topic:     topic,
+		}
+	}
+}
+
+// connectionCheckTickCmd periodically probes traQ while offline so that
+// the app can leave the read-only mode once the server is reachable again.
+func (m *Model) connectionCheckTickCmd() tea.Cmd {
+	return tea.Tick(connectionCheckInterval, func(time.Time) tea.Msg {
+		if m.traqContext.IsOffline() {
+			_ = m.traqContext.CheckConnection(context.Background())
+		}
+
+		return connectionCheckMsg{}
+	})
+}
+
+// fetchPresenceCmd refetches the online users, which the other models show
+// once they receive shared.PresenceUpdatedMsg.
+func (m *Model) fetchPresenceCmd(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		if m.traqContext.IsOffline() {
+			return nil
+		}
+
+		// the stale users are returned while the cache refreshes them, so that no one
+		// looks offline in the meantime; they are shown on the next refresh
+		if _, err := m.traqContext.OnlineUsers.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "load online users", "error", err)
+			return nil
+		}
+
+		return shared.PresenceUpdatedMsg{}
+	}
+}
+
+func (m *Model) presenceTickCmd() tea.Cmd {
+	return tea.Tick(presenceRefreshInterval, func(time.Time) tea.Msg {
+		return presenceTickMsg{}
+	})
+}
+
 func (m *Model) View() string {
-	leftPart := lipgloss.JoinHorizontal(
-		lipgloss.Center,
+	leftParts := []string{
 		m.theme.Header.Title.Render("lazytraq"),
 		" in ",
 		m.theme.Header.Host.Render(m.apiHost),
-	)
+	}
+	if m.traqContext.IsOffline() {
+		leftParts = append(leftParts, " ", m.theme.Header.Offline.Render("offline (read-only)"))
+	}
+	leftPart := lipgloss.JoinHorizontal(lipgloss.Center, leftParts...)
 
 	username := "@uknown"
 	if m.state.me != nil {
 		username = fmt.Sprintf("@%s", m.state.me.Name)
 	}
+
+	// the topic takes the space left between the host and the username
+	if channel := m.channelView(); channel != "" {
+		available := m.w - lipgloss.Width(leftPart) - lipgloss.Width(username) - 2
+		leftPart += m.theme.Header.Topic.Render(ansi.Truncate(channel, max(available, 0), "…"))
+	}
+
 	rightPart := m.theme.Header.Username.
 		Width(m.w - lipgloss.Width(leftPart) - 1).
 		Align(lipgloss.Right).
@@ -84,3 +207,25 @@ func (m *Model) View() string {
 		),
 	)
 }
+
+// channelView describes the open channel, e.g. " · #general: lunch at noon".
+func (m *Model) channelView() string {
+	if m.state.channelID == uuid.Nil {
+		return ""
+	}
+
+	parts := make([]string, 0, 2)
+	if path, ok := m.traqContext.ChannelPath(m.state.channelID); ok {
+		parts = append(parts, "#"+path)
+	}
+
+	if topic := strings.Join(strings.Fields(m.state.topic), " "); topic != "" {
+		parts = append(parts, topic)
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return " · " + strings.Join(parts, ": ")
+}
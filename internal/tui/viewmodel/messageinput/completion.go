@@ -0,0 +1,452 @@
+package messageinput
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/blacktop/go-termimg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/kujtimiihoxha/vimtea"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/sahilm/fuzzy"
+)
+
+const (
+	// maxCompletionItems is the number of candidates shown in the popup at once.
+	maxCompletionItems = 5
+	// maxCompletionMatches bounds the number of candidates kept for a query.
+	maxCompletionMatches = 50
+)
+
+// embeddablePattern matches words that may have been completed into an embed.
+var embeddablePattern = regexp.MustCompile(`[@#][\w\-/]+`)
+
+// completionCandidate is a suggestion in the completion popup.
+type completionCandidate struct {
+	// label is matched against the query and shown in the popup.
+	label  string
+	detail string
+	// text replaces the trigger and the query when the candidate is accepted.
+	text string
+	// embed is sent in place of text, e.g. a mention that notifies the user.
+	embed string
+	// stampID is the stamp previewed next to the candidate, if any.
+	stampID uuid.UUID
+	// userID is the user whose presence is shown next to the candidate, if any.
+	userID uuid.UUID
+}
+
+// completionSource suggests candidates for words starting with trigger.
+type completionSource struct {
+	trigger    byte
+	candidates func() []completionCandidate
+}
+
+// completion is an ongoing completion of the word typed after a trigger.
+type completion struct {
+	source   *completionSource
+	row, col int // position of the trigger
+	query    string
+	matches  []completionCandidate
+	selected int
+}
+
+type candidateList []completionCandidate
+
+func (l candidateList) String(i int) string { return l[i].label }
+func (l candidateList) Len() int            { return len(l) }
+
+func (c *completion) setQuery(query string) {
+	c.query = query
+	c.selected = 0
+
+	candidates := c.source.candidates()
+	if query == "" {
+		c.matches = candidates[:min(len(candidates), maxCompletionMatches)]
+		return
+	}
+
+	matches := fuzzy.FindFrom(query, candidateList(candidates))
+	c.matches = make([]completionCandidate, 0, min(len(matches), maxCompletionMatches))
+	for _, match := range matches[:min(len(matches), maxCompletionMatches)] {
+		c.matches = append(c.matches, candidates[match.Index])
+	}
+}
+
+func (c *completion) move(delta int) {
+	if len(c.matches) == 0 {
+		return
+	}
+
+	c.selected = (c.selected + delta + len(c.matches)) % len(c.matches)
+}
+
+// mentionCandidates suggests users and user groups known to the caches.
+func (m *Model) mentionCandidates() []completionCandidate {
+	users, _ := m.traqContext.Users.GetIfExists(struct{}{})
+	groups, _ := m.traqContext.UserGroups.GetIfExists(struct{}{})
+
+	candidates := make([]completionCandidate, 0, len(users)+len(groups))
+	for _, user := range users {
+		if user.GetState() != traqapi.UserAccountState1 {
+			continue
+		}
+
+		text := "@" + user.GetName()
+		candidates = append(candidates, completionCandidate{
+			label:  user.GetName(),
+			detail: user.GetDisplayName(),
+			text:   text,
+			embed:  traqapiext.FormatEmbed("user", text, user.GetID()),
+			userID: user.GetID(),
+		})
+	}
+
+	for _, group := range groups {
+		text := "@" + group.GetName()
+		candidates = append(candidates, completionCandidate{
+			label:  group.GetName(),
+			detail: "group",
+			text:   text,
+			embed:  traqapiext.FormatEmbed("group", text, group.GetID()),
+		})
+	}
+
+	return candidates
+}
+
+// channelCandidates suggests the paths of public channels that are not archived.
+func (m *Model) channelCandidates() []completionCandidate {
+	channels, ok := m.traqContext.Channels.GetIfExists(struct{}{})
+	if !ok || channels == nil {
+		return nil
+	}
+
+	candidates := make([]completionCandidate, 0, len(channels.Public))
+	for _, channel := range channels.Public {
+		if channel.GetArchived() {
+			continue
+		}
+
+		path, ok := m.traqContext.ChannelPath(channel.GetID())
+		if !ok {
+			continue
+		}
+
+		text := "#" + path
+		candidates = append(candidates, completionCandidate{
+			label:  path,
+			detail: channel.GetTopic(),
+			text:   text,
+			embed:  traqapiext.FormatEmbed("channel", text, channel.GetID()),
+		})
+	}
+
+	slices.SortFunc(candidates, func(a, b completionCandidate) int {
+		return strings.Compare(a.label, b.label)
+	})
+
+	return candidates
+}
+
+// stampCandidates suggests stamps known to the cache.
+func (m *Model) stampCandidates() []completionCandidate {
+	stamps, _ := m.traqContext.Stamps.GetIfExists(struct{}{})
+
+	candidates := make([]completionCandidate, 0, len(stamps))
+	for _, stamp := range stamps {
+		candidates = append(candidates, completionCandidate{
+			label:   stamp.GetName(),
+			text:    ":" + stamp.GetName() + ":",
+			stampID: stamp.GetID(),
+		})
+	}
+
+	return candidates
+}
+
+// handleCompletionKey handles the keys that operate the completion popup.
+// It reports false if the key should be passed to the editor.
+func (m *Model) handleCompletionKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if m.completion == nil {
+		return nil, false
+	}
+
+	switch msg.String() {
+	case "tab", "enter":
+		return m.acceptCompletion(), true
+
+	case "up", "ctrl+p":
+		m.completion.move(-1)
+		return m.fetchCompletionStampsCmd(), true
+
+	case "down", "ctrl+n":
+		m.completion.move(1)
+		return m.fetchCompletionStampsCmd(), true
+
+	case "esc":
+		return m.closeCompletion(), true
+	}
+
+	return nil, false
+}
+
+// updateCompletion starts, updates or closes the completion after the editor
+// has handled msg. before is the buffer content before the key was handled.
+func (m *Model) updateCompletion(msg tea.KeyMsg, before []string) tea.Cmd {
+	if m.editor.GetMode() != vimtea.ModeInsert {
+		return m.closeCompletion()
+	}
+
+	lines := m.editor.GetBuffer().Lines()
+	row, col, ok := editedPosition(before, lines)
+	if !ok {
+		return m.closeCompletion()
+	}
+
+	if m.completion == nil {
+		source := m.completionSourceFor(msg.String())
+		if source == nil || col == 0 || lines[row][col-1] != source.trigger {
+			return nil
+		}
+
+		// triggers only start a completion at the beginning of a word
+		if col >= 2 && !isSpace(lines[row][col-2]) {
+			return nil
+		}
+
+		m.completion = &completion{
+			source: source,
+			row:    row,
+			col:    col - 1,
+		}
+	}
+
+	c := m.completion
+	if row != c.row || col <= c.col || lines[row][c.col] != c.source.trigger {
+		return m.closeCompletion()
+	}
+
+	// a space or another trigger, e.g. the closing colon of a stamp, ends the word
+	query := lines[row][c.col+1 : col]
+	if strings.ContainsAny(query, " \t"+string(c.source.trigger)) {
+		return m.closeCompletion()
+	}
+
+	c.setQuery(query)
+
+	return tea.Batch(m.resizeEditor(), m.fetchCompletionStampsCmd())
+}
+
+func (m *Model) completionSourceFor(key string) *completionSource {
+	if len(key) != 1 {
+		return nil
+	}
+
+	for i := range m.completionSources {
+		if m.completionSources[i].trigger == key[0] {
+			return &m.completionSources[i]
+		}
+	}
+
+	return nil
+}
+
+// acceptCompletion replaces the trigger and the query with the selected candidate.
+func (m *Model) acceptCompletion() tea.Cmd {
+	c := m.completion
+	if len(c.matches) == 0 {
+		return m.closeCompletion()
+	}
+
+	candidate := c.matches[c.selected]
+	b := m.editor.GetBuffer()
+	b.DeleteAt(c.row, c.col, c.row, c.col+len(c.query))
+	b.InsertAt(c.row, c.col, candidate.text+" ")
+	if candidate.embed != "" {
+		m.embeds()[candidate.text] = candidate.embed
+	}
+
+	m.moveCursor(c.row, c.col+len(candidate.text)+1)
+
+	return m.closeCompletion()
+}
+
+func (m *Model) closeCompletion() tea.Cmd {
+	if m.completion == nil {
+		return nil
+	}
+
+	m.completion = nil
+
+	return m.resizeEditor()
+}
+
+// moveCursor moves the editor cursor. vimtea has no API for this, so it replays
+// the message vimtea uses to restore the cursor on undo and clears its status.
+func (m *Model) moveCursor(row, col int) {
+	m.updateEditor(vimtea.UndoRedoMsg{
+		NewCursor: vimtea.Cursor{Row: row, Col: col},
+		Success:   true,
+	})
+	m.updateEditor(vimtea.SetStatusMsg("")())
+}
+
+// visibleCandidates returns the candidates shown in the popup and the index of the first one.
+func (m *Model) visibleCandidates() ([]completionCandidate, int) {
+	c := m.completion
+	visible := m.completionHeight() - 1
+	start := max(c.selected-visible+1, 0)
+
+	return c.matches[start:min(start+visible, len(c.matches))], start
+}
+
+// fetchCompletionStampsCmd loads the images of the stamps shown in the popup.
+func (m *Model) fetchCompletionStampsCmd() tea.Cmd {
+	if m.completion == nil {
+		return nil
+	}
+
+	candidates, _ := m.visibleCandidates()
+	cmds := make([]tea.Cmd, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.stampID == uuid.Nil {
+			continue
+		}
+
+		if _, ok := m.state.requestedStamps[candidate.stampID]; ok {
+			continue
+		}
+
+		m.state.requestedStamps[candidate.stampID] = struct{}{}
+		cmds = append(cmds, m.fetchStampImageCmd(context.Background(), candidate.stampID))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// stampPreview renders the stamp image in two cells, or blanks until it is loaded.
+func (m *Model) stampPreview(stampID uuid.UUID) string {
+	if preview, ok := m.state.stampPreviews[stampID]; ok {
+		return preview
+	}
+
+	img, ok := m.state.stampImages[stampID]
+	if !ok {
+		return "  "
+	}
+
+	// halfblocks keep the aspect ratio, so a square stamp in one row takes two cells
+	preview, err := termimg.New(img).
+		Protocol(termimg.Halfblocks).
+		Size(4, 1).
+		Render()
+	if err != nil {
+		preview = ""
+	}
+
+	preview = lipgloss.NewStyle().
+		Width(2).
+		MaxWidth(2).
+		MaxHeight(1).
+		Render(strings.TrimRight(preview, "\n"))
+	m.state.stampPreviews[stampID] = preview
+
+	return preview
+}
+
+// completionHeight returns the number of lines the completion popup occupies.
+func (m *Model) completionHeight() int {
+	if m.completion == nil {
+		return 0
+	}
+
+	// the border and at least one item
+	return 1 + max(min(len(m.completion.matches), maxCompletionItems, m.h-4), 1)
+}
+
+func (m *Model) completionView() string {
+	c := m.completion
+	visible := m.completionHeight() - 1
+	if len(c.matches) == 0 {
+		return m.theme.MessageInput.Completion.Width(m.w).Render(
+			m.theme.MessageInput.CompletionDetail.Render("no matches"),
+		)
+	}
+
+	candidates, start := m.visibleCandidates()
+	items := make([]string, 0, visible)
+	for i, candidate := range candidates {
+		style := m.theme.MessageInput.CompletionItem
+		if start+i == c.selected {
+			style = m.theme.MessageInput.CompletionSelected
+		}
+
+		item := style.Render(candidate.text) + m.theme.MessageInput.CompletionDetail.Render(candidate.detail)
+		if candidate.stampID != uuid.Nil {
+			item = " " + m.stampPreview(candidate.stampID) + item
+		}
+		if candidate.userID != uuid.Nil {
+			item = " " + m.theme.PresenceDot(m.traqContext.IsOnline(candidate.userID)) + item
+		}
+		items = append(items, ansi.Truncate(item, m.w, "…"))
+	}
+
+	return m.theme.MessageInput.Completion.Width(m.w).Render(
+		lipgloss.JoinVertical(lipgloss.Left, items...),
+	)
+}
+
+// expandEmbeds replaces the completed words in content with their embeds.
+func expandEmbeds(content string, embeds map[string]string) string {
+	return embeddablePattern.ReplaceAllStringFunc(content, func(s string) string {
+		if embed, ok := embeds[s]; ok {
+			return embed
+		}
+
+		return s
+	})
+}
+
+// editedPosition returns the position right after a single-line edit from before
+// to after, which is where the cursor is after typing or deleting a character.
+func editedPosition(before, after []string) (row, col int, ok bool) {
+	if len(before) != len(after) {
+		return 0, 0, false
+	}
+
+	row = -1
+	for i := range after {
+		if before[i] == after[i] {
+			continue
+		}
+
+		if row >= 0 {
+			return 0, 0, false
+		}
+
+		row = i
+	}
+
+	if row < 0 {
+		return 0, 0, false
+	}
+
+	o, n := before[row], after[row]
+	prefix := 0
+	for prefix < min(len(o), len(n)) && o[prefix] == n[prefix] {
+		prefix++
+	}
+
+	return row, prefix + max(len(n)-len(o), 0), true
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
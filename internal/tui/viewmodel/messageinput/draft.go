@@ -0,0 +1,150 @@
+package messageinput
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+// draftSaveDelay is how long edits are left unsaved, so that typing does not write
+// the drafts to disk on every key.
+const draftSaveDelay = time.Second
+
+type draftSaveMsg struct {
+	seq int
+}
+
+// switchChannel saves the draft of the current channel and restores the one of channelID.
+func (m *Model) switchChannel(channelID uuid.UUID) tea.Cmd {
+	if channelID == m.state.channelID {
+		return nil
+	}
+
+	cmd := m.saveDraft()
+
+	editor, ok := m.state.editors[channelID]
+	if !ok {
+		draft, _ := m.traqContext.Drafts.Get(channelID)
+		editor = m.newEditor(draft.Content, m.sendMessage)
+		m.state.editors[channelID] = editor
+		m.state.embeds[channelID] = maps.Clone(draft.Embeds)
+	}
+
+	m.state.channelID = channelID
+	m.editor = editor
+	m.completion = nil
+
+	return tea.Batch(cmd, m.resizeEditor())
+}
+
+// restoreDraft appends content to the draft and moves the cursor to its end.
+func (m *Model) restoreDraft(content string) {
+	b := m.editor.GetBuffer()
+	lines := b.Lines()
+	row, col := len(lines)-1, len(lines[len(lines)-1])
+	if strings.TrimSpace(b.Text()) != "" {
+		content = "\n" + content
+	}
+
+	b.InsertAt(row, col, content)
+
+	lines = b.Lines()
+	m.moveCursor(len(lines)-1, len(lines[len(lines)-1]))
+}
+
+// mentionUser appends a mention of the user to the draft and moves the cursor to its end.
+func (m *Model) mentionUser(userID uuid.UUID, name string) {
+	text := "@" + name
+	b := m.editor.GetBuffer()
+	lines := b.Lines()
+	row, col := len(lines)-1, len(lines[len(lines)-1])
+	content := text + " "
+	if col > 0 && !strings.HasSuffix(lines[row], " ") {
+		content = " " + content
+	}
+
+	b.InsertAt(row, col, content)
+	m.embeds()[text] = traqapiext.FormatEmbed("user", text, userID)
+
+	m.moveCursor(row, col+len(content))
+}
+
+// embeds returns the embeds of the completed words in the current draft,
+// or in the message being edited.
+func (m *Model) embeds() map[string]string {
+	if m.state.editing != nil {
+		return m.state.editing.embeds
+	}
+
+	embeds, ok := m.state.embeds[m.state.channelID]
+	if !ok || embeds == nil {
+		embeds = make(map[string]string)
+		m.state.embeds[m.state.channelID] = embeds
+	}
+
+	return embeds
+}
+
+func (m *Model) scheduleDraftSave() tea.Cmd {
+	m.state.draftSeq++
+	seq := m.state.draftSeq
+
+	return tea.Tick(draftSaveDelay, func(time.Time) tea.Msg {
+		return draftSaveMsg{seq: seq}
+	})
+}
+
+// saveDraft stores the current draft and notifies the channel tree if the channel
+// got or lost its draft.
+func (m *Model) saveDraft() tea.Cmd {
+	changed, err := m.storeDraft()
+	if err != nil {
+		slog.Warn("save draft", "channelID", m.state.channelID, "error", err)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return func() tea.Msg {
+		return shared.DraftsChangedMsg{}
+	}
+}
+
+func (m *Model) storeDraft() (changed bool, err error) {
+	if m.state.channelID == uuid.Nil {
+		return false, nil
+	}
+
+	// the editor shows the edited message instead of the draft while editing
+	editor, ok := m.state.editors[m.state.channelID]
+	if !ok {
+		return false, nil
+	}
+
+	_, had := m.traqContext.Drafts.Get(m.state.channelID)
+	err = m.traqContext.Drafts.Set(m.state.channelID, traqapiext.Draft{
+		Content: editor.GetBuffer().Text(),
+		Embeds:  maps.Clone(m.state.embeds[m.state.channelID]),
+	})
+	if err != nil {
+		return false, fmt.Errorf("set draft: %w", err)
+	}
+
+	_, has := m.traqContext.Drafts.Get(m.state.channelID)
+
+	return had != has, nil
+}
+
+// Close saves the current draft.
+func (m *Model) Close() error {
+	_, err := m.storeDraft()
+	return err
+}
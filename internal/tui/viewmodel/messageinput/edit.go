@@ -0,0 +1,111 @@
+package messageinput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/kujtimiihoxha/vimtea"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const editingBanner = "editing message · enter: save · esc: cancel"
+
+// editTarget is the message being edited in place of the draft of its channel.
+type editTarget struct {
+	channelID uuid.UUID
+	messageID uuid.UUID
+	// embeds maps the mentions and channel links in the edited content to their embeds.
+	embeds map[string]string
+}
+
+type editFailedMsg struct {
+	messageID uuid.UUID
+	err       error
+}
+
+// startEditing replaces the draft in the editor with the message. The draft is kept
+// and comes back when editing ends.
+func (m *Model) startEditing(msg shared.EditMessageMsg) tea.Cmd {
+	m.state.editing = nil
+	cmd := m.switchChannel(msg.ChannelID)
+
+	content, embeds := traqapiext.CollapseEmbeds(msg.Content)
+	m.state.editing = &editTarget{
+		channelID: msg.ChannelID,
+		messageID: msg.MessageID,
+		embeds:    embeds,
+	}
+	m.editor = m.newEditor(content, m.submitEdit)
+	m.completion = nil
+	m.editor.SetMode(vimtea.ModeInsert)
+
+	lines := m.editor.GetBuffer().Lines()
+	m.moveCursor(len(lines)-1, len(lines[len(lines)-1]))
+
+	return tea.Batch(cmd, m.resizeEditor())
+}
+
+// stopEditing discards the edit and brings back the draft of the channel.
+func (m *Model) stopEditing() tea.Cmd {
+	if m.state.editing == nil {
+		return nil
+	}
+
+	m.state.editing = nil
+	m.editor = m.state.editors[m.state.channelID]
+	m.completion = nil
+
+	return m.resizeEditor()
+}
+
+// submitEdit saves the edited message. The editor is left as is until traQ accepts it.
+func (m *Model) submitEdit(b vimtea.Buffer) tea.Cmd {
+	target := *m.state.editing
+	content := expandEmbeds(b.Text(), target.embeds)
+
+	return func() tea.Msg {
+		ctx := context.Background()
+		err := m.traqContext.EditMessage(ctx, target.channelID, target.messageID, content)
+		if err != nil {
+			slog.WarnContext(ctx, "edit message", "messageID", target.messageID, "error", err)
+			return editFailedMsg{
+				messageID: target.messageID,
+				err:       err,
+			}
+		}
+
+		return shared.MessageEditedMsg{
+			ChannelID: target.channelID,
+			MessageID: target.messageID,
+		}
+	}
+}
+
+// editFailedStatus describes why a message could not be edited.
+func editFailedStatus(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: cannot edit messages"
+	}
+
+	return fmt.Sprintf("failed to edit: %v", err)
+}
+
+func (m *Model) bannerHeight() int {
+	if m.state.editing == nil {
+		return 0
+	}
+
+	return 1
+}
+
+func (m *Model) bannerView() string {
+	return lipgloss.NewStyle().
+		MaxWidth(m.w).
+		Render(m.theme.MessageInput.Banner.Render(editingBanner))
+}
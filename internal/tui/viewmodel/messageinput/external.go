@@ -0,0 +1,86 @@
+package messageinput
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kujtimiihoxha/vimtea"
+)
+
+type externalEditorClosedMsg struct {
+	path string
+	err  error
+}
+
+// openExternalEditorCmd writes the buffer to a temporary markdown file and suspends
+// the TUI to edit it with $VISUAL or $EDITOR.
+func (m *Model) openExternalEditorCmd() tea.Cmd {
+	f, err := os.CreateTemp("", "lazytraq-*.md")
+	if err != nil {
+		return vimtea.SetStatusMsg(fmt.Sprintf("create temp file: %v", err))
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(m.editor.GetBuffer().Text()); err != nil {
+		_ = os.Remove(f.Name())
+		return vimtea.SetStatusMsg(fmt.Sprintf("write temp file: %v", err))
+	}
+
+	// the editor may come with arguments, e.g. "code --wait"
+	args := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"))
+	args = append(args, f.Name())
+	path := f.Name()
+
+	return tea.ExecProcess(exec.Command(args[0], args[1:]...), func(err error) tea.Msg {
+		return externalEditorClosedMsg{
+			path: path,
+			err:  err,
+		}
+	})
+}
+
+// loadExternalEdit replaces the buffer with the content written by the external editor
+// and leaves the editor in normal mode, ready to send.
+func (m *Model) loadExternalEdit(msg externalEditorClosedMsg) tea.Cmd {
+	defer func() {
+		if err := os.Remove(msg.path); err != nil {
+			slog.Warn("remove temp file", "path", msg.path, "error", err)
+		}
+	}()
+
+	if msg.err != nil {
+		return vimtea.SetStatusMsg(fmt.Sprintf("external editor: %v", msg.err))
+	}
+
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		return vimtea.SetStatusMsg(fmt.Sprintf("read temp file: %v", err))
+	}
+
+	// most editors end files with a newline
+	content := strings.TrimSuffix(string(data), "\n")
+
+	b := m.editor.GetBuffer()
+	if content == b.Text() {
+		return nil
+	}
+
+	lines := b.Lines()
+	b.DeleteAt(0, 0, len(lines)-1, len(lines[len(lines)-1]))
+	b.InsertAt(0, 0, content)
+
+	lines = b.Lines()
+	m.moveCursor(len(lines)-1, len(lines[len(lines)-1]))
+	m.editor.SetMode(vimtea.ModeNormal)
+
+	if m.state.editing != nil {
+		return nil
+	}
+
+	return m.scheduleDraftSave()
+}
@@ -2,132 +2,347 @@ package messageinput
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"image"
+	"log/slog"
+	"slices"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/uuid"
 	"github.com/kujtimiihoxha/vimtea"
-	"github.com/ras0q/lazytraq/internal/traqapi"
 	"github.com/ras0q/lazytraq/internal/traqapiext"
 	"github.com/ras0q/lazytraq/internal/tui/shared"
 )
 
+type (
+	stampImageFetchedMsg struct {
+		stampID uuid.UUID
+		img     image.Image
+	}
+)
+
 type State struct {
 	channelID uuid.UUID
+	// editors holds an editor per channel, so that each keeps its own draft and history.
+	editors map[uuid.UUID]vimtea.Editor
+	// embeds maps completed mentions and channel links in each draft to the embeds sent for them.
+	embeds   map[uuid.UUID]map[string]string
+	draftSeq int
+	// editing is the message being edited, or nil while writing a new message.
+	editing        *editTarget
+	previewEnabled bool
+	previewSeq     int
+	// preview is the rendered buffer, updated after typing pauses.
+	preview         string
+	stampImages     map[uuid.UUID]image.Image
+	stampPreviews   map[uuid.UUID]string
+	requestedStamps map[uuid.UUID]struct{}
 }
 
 type Model struct {
-	w, h        int
-	traqContext *traqapiext.Context
-	editor      vimtea.Editor
+	w, h              int
+	traqContext       *traqapiext.Context
+	editor            vimtea.Editor
+	theme             shared.Theme
+	completionSources []completionSource
+	completion        *completion
+	previewRenderer   *glamour.TermRenderer
 
 	state State
 }
 
 // New creates a new message input model.
-func New(w, h int, traqContext *traqapiext.Context) *Model {
-	editor := vimtea.NewEditor(
-		vimtea.WithEnableStatusBar(true),
-	)
-
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
 	m := &Model{
 		w:           w,
 		h:           h,
 		traqContext: traqContext,
-		editor:      editor,
+		theme:       theme,
+		state: State{
+			editors:         make(map[uuid.UUID]vimtea.Editor),
+			embeds:          make(map[uuid.UUID]map[string]string),
+			stampImages:     make(map[uuid.UUID]image.Image),
+			stampPreviews:   make(map[uuid.UUID]string),
+			requestedStamps: make(map[uuid.UUID]struct{}),
+		},
+	}
+
+	m.completionSources = []completionSource{
+		{trigger: '@', candidates: m.mentionCandidates},
+		{trigger: '#', candidates: m.channelCandidates},
+		{trigger: ':', candidates: m.stampCandidates},
 	}
 
+	m.editor = m.newEditor("", m.sendMessage)
+	m.state.editors[uuid.Nil] = m.editor
+
+	return m
+}
+
+// newEditor creates an editor that passes its buffer to submit on enter in normal mode.
+func (m *Model) newEditor(content string, submit func(b vimtea.Buffer) tea.Cmd) vimtea.Editor {
+	editor := vimtea.NewEditor(
+		vimtea.WithContent(content),
+		vimtea.WithEnableStatusBar(true),
+	)
+
 	editor.AddBinding(vimtea.KeyBinding{
 		Key:         "enter",
 		Mode:        vimtea.ModeNormal,
 		Description: "Send message",
 		Handler: func(b vimtea.Buffer) tea.Cmd {
-			content := b.Text()
-			if len(content) == 0 {
+			if len(b.Text()) == 0 {
 				return nil
 			}
 
-			for i, line := range b.Lines() {
-				b.DeleteAt(i, 0, i, len(line))
-			}
-			return m.sendMessageCmd(context.Background(), m.state.channelID, content)
+			return submit(b)
 		},
 	})
 
-	return m
+	return editor
+}
+
+// sendMessage queues the draft for sending and clears the editor.
+func (m *Model) sendMessage(b vimtea.Buffer) tea.Cmd {
+	content := b.Text()
+	lines := b.Lines()
+	b.DeleteAt(0, 0, len(lines)-1, len(lines[len(lines)-1]))
+	m.moveCursor(0, 0)
+
+	content = expandEmbeds(content, m.embeds())
+	clear(m.embeds())
+	m.traqContext.Outbox.Enqueue(m.state.channelID, content)
+
+	cmds := []tea.Cmd{m.saveDraft()}
+	if m.traqContext.IsOffline() {
+		cmds = append(cmds, vimtea.SetStatusMsg("offline: message queued"))
+	}
+
+	return tea.Batch(cmds...)
 }
 
 func (m *Model) Init() tea.Cmd {
-	_, cmd := m.editor.SetSize(m.w, m.h)
+	return tea.Batch(
+		m.resizeEditor(),
+		m.prefetchCompletionsCmd(context.Background()),
+	)
+}
+
+// CapturesKey reports whether the key is used by the editor even though it is bound
+// globally, e.g. n and q while typing or editing a message.
+func (m *Model) CapturesKey(key string) bool {
+	if key == "ctrl+c" {
+		return false
+	}
+
+	return m.editor.GetMode() == vimtea.ModeInsert || m.state.editing != nil
+}
+
+func (m *Model) SetSize(w, h int) tea.Cmd {
+	m.w, m.h = w, h
+	if m.state.previewEnabled {
+		m.previewRenderer = shared.NewMarkdownRenderer(m.previewWidth())
+		m.renderPreview()
+	}
+
+	return m.resizeEditor()
+}
+
+// resizeEditor fits the editor into the space left by the banner, the completion popup
+// and the preview.
+func (m *Model) resizeEditor() tea.Cmd {
+	_, cmd := m.editor.SetSize(m.editorWidth(), m.editorHeight())
 	return cmd
 }
 
+func (m *Model) editorHeight() int {
+	return m.h - m.bannerHeight() - m.completionHeight()
+}
+
+// Update handles msg and schedules a preview if the buffer changed, whichever way it did.
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
+	before := m.editor.GetBuffer().Text()
+	cmd := m.update(msg)
+	if m.editor.GetBuffer().Text() != before {
+		cmd = tea.Batch(cmd, m.schedulePreview())
+	}
+
+	return m, cmd
+}
 
+func (m *Model) update(msg tea.Msg) tea.Cmd {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if cmd, ok := m.handleCompletionKey(msg); ok {
+			return cmd
+		}
+
 		switch msg.String() {
 		case "esc":
 			if m.editor.GetMode() == vimtea.ModeNormal {
-				return m, func() tea.Msg {
+				if m.state.editing != nil {
+					return m.stopEditing()
+				}
+
+				return func() tea.Msg {
 					return shared.ReturnToSidebarMsg{}
 				}
 			}
 
 			m.editor.SetMode(vimtea.ModeNormal)
+
+		case "ctrl+o":
+			return m.togglePreview()
+
+		case "ctrl+e":
+			m.completion = nil
+			return tea.Batch(m.resizeEditor(), m.openExternalEditorCmd())
 		}
 
+		before := slices.Clone(m.editor.GetBuffer().Lines())
+		cmds := []tea.Cmd{m.updateEditor(msg), m.updateCompletion(msg, before)}
+		if m.state.editing == nil && !slices.Equal(before, m.editor.GetBuffer().Lines()) {
+			cmds = append(cmds, m.scheduleDraftSave())
+		}
+
+		return tea.Batch(cmds...)
+
 	case shared.FocusMessageInputMsg:
-		m.state.channelID = msg.ChannelID
+		// coming back to the channel keeps the edit in progress
+		var cmd tea.Cmd
+		if msg.ChannelID != m.state.channelID {
+			cmd = tea.Batch(m.stopEditing(), m.switchChannel(msg.ChannelID))
+		}
+		m.editor.SetMode(vimtea.ModeInsert)
+
+		return cmd
+
+	case shared.RestoreDraftMsg:
+		cmd := tea.Batch(m.stopEditing(), m.switchChannel(msg.ChannelID))
+		m.restoreDraft(msg.Content)
 		m.editor.SetMode(vimtea.ModeInsert)
+
+		return tea.Batch(cmd, m.saveDraft())
+
+	case shared.MentionUserMsg:
+		cmd := tea.Batch(m.stopEditing(), m.switchChannel(msg.ChannelID))
+		m.mentionUser(msg.UserID, msg.Name)
+		m.editor.SetMode(vimtea.ModeInsert)
+
+		return tea.Batch(cmd, m.saveDraft())
+
+	case shared.EditMessageMsg:
+		return m.startEditing(msg)
+
+	case shared.MessageEditedMsg:
+		if m.state.editing == nil || m.state.editing.messageID != msg.MessageID {
+			return nil
+		}
+
+		return m.stopEditing()
+
+	case editFailedMsg:
+		if m.state.editing == nil || m.state.editing.messageID != msg.messageID {
+			return nil
+		}
+
+		return vimtea.SetStatusMsg(editFailedStatus(msg.err))
+
+	case draftSaveMsg:
+		if msg.seq != m.state.draftSeq {
+			return nil
+		}
+
+		return m.saveDraft()
+
+	case previewRenderMsg:
+		if msg.seq != m.state.previewSeq || !m.state.previewEnabled {
+			return nil
+		}
+
+		m.renderPreview()
+		return nil
+
+	case externalEditorClosedMsg:
+		return m.loadExternalEdit(msg)
+
+	case stampImageFetchedMsg:
+		m.state.stampImages[msg.stampID] = msg.img
+		return nil
 	}
 
+	return m.updateEditor(msg)
+}
+
+func (m *Model) updateEditor(msg tea.Msg) tea.Cmd {
 	_editor, cmd := m.editor.Update(msg)
 	m.editor = _editor.(vimtea.Editor)
 
-	return m, cmd
+	return cmd
 }
 
 func (m *Model) View() string {
+	views := make([]string, 0, 3)
+	if m.state.editing != nil {
+		views = append(views, m.bannerView())
+	}
+	if m.state.previewEnabled {
+		views = append(views, lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			m.editor.View(),
+			m.previewView(m.editorHeight()),
+		))
+	} else {
+		views = append(views, m.editor.View())
+	}
+	if m.completion != nil {
+		views = append(views, m.completionView())
+	}
+
 	return lipgloss.NewStyle().
 		Width(m.w).
 		Height(m.h).
 		Render(
-			m.editor.View(),
+			lipgloss.JoinVertical(lipgloss.Left, views...),
 		)
 }
 
-func (m *Model) sendMessageCmd(ctx context.Context, channelID uuid.UUID, content string) tea.Cmd {
+// prefetchCompletionsCmd loads the users, user groups, channels and stamps suggested
+// by the completion, so that it can look them up without blocking.
+func (m *Model) prefetchCompletionsCmd(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
-		res, err := m.traqContext.PostMessage(
-			ctx,
-			traqapi.PostMessageRequest{
-				Content: content,
-				Embed:   traqapi.NewOptBool(true),
-			},
-			channelID,
-		)
-		if err != nil {
-			return shared.ErrorMsg(fmt.Errorf("post message to traQ: %w", err))
+		if _, err := m.traqContext.Users.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "prefetch users for completion", "error", err)
 		}
 
-		switch res := res.(type) {
-		case *traqapi.Message:
-			return shared.MessageSentMsg{
-				MessageID: res.ID,
-			}
+		if _, err := m.traqContext.UserGroups.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "prefetch user groups for completion", "error", err)
+		}
+
+		if _, err := m.traqContext.Channels.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "prefetch channels for completion", "error", err)
+		}
+
+		if _, err := m.traqContext.Stamps.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "prefetch stamps for completion", "error", err)
+		}
 
-		case *traqapi.PostMessageBadRequest:
-			return shared.ErrorMsg(errors.New("post message to traQ: bad request"))
+		return nil
+	}
+}
 
-		case *traqapi.PostMessageNotFound:
-			return shared.ErrorMsg(errors.New("post message to traQ: not found"))
+func (m *Model) fetchStampImageCmd(ctx context.Context, stampID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		img, err := m.traqContext.StampImages.Get(ctx, stampID)
+		if err != nil {
+			slog.WarnContext(ctx, "load stamp image for completion", "stampID", stampID, "error", err)
+			return nil
+		}
 
-		default:
-			return shared.ErrorMsg(errors.New("post message to traQ: unreachable error"))
+		return stampImageFetchedMsg{
+			stampID: stampID,
+			img:     img,
 		}
 	}
 }
@@ -0,0 +1,94 @@
+package messageinput
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+// previewDelay is how long typing has to pause before the preview is rendered again.
+const previewDelay = 300 * time.Millisecond
+
+type previewRenderMsg struct {
+	seq int
+}
+
+// togglePreview shows or hides the preview next to the editor.
+func (m *Model) togglePreview() tea.Cmd {
+	m.state.previewEnabled = !m.state.previewEnabled
+	m.state.preview = ""
+	if m.state.previewEnabled {
+		m.previewRenderer = shared.NewMarkdownRenderer(m.previewWidth())
+		m.renderPreview()
+	}
+
+	return m.resizeEditor()
+}
+
+func (m *Model) schedulePreview() tea.Cmd {
+	if !m.state.previewEnabled {
+		return nil
+	}
+
+	m.state.previewSeq++
+	seq := m.state.previewSeq
+
+	return tea.Tick(previewDelay, func(time.Time) tea.Msg {
+		return previewRenderMsg{seq: seq}
+	})
+}
+
+// renderPreview renders the buffer the way channelcontent renders messages.
+func (m *Model) renderPreview() {
+	content := expandEmbeds(m.editor.GetBuffer().Text(), m.embeds())
+	if strings.TrimSpace(content) == "" || m.previewRenderer == nil {
+		m.state.preview = ""
+		return
+	}
+
+	m.state.preview = shared.RenderMarkdown(
+		m.traqContext,
+		m.previewRenderer,
+		m.theme.ChannelContent,
+		content,
+		traqapiext.MarkdownOptions{},
+	)
+}
+
+// editorWidth is the width left to the editor by the preview.
+func (m *Model) editorWidth() int {
+	if !m.state.previewEnabled {
+		return m.w
+	}
+
+	return m.w / 2
+}
+
+func (m *Model) previewWidth() int {
+	// the border of the preview takes a column
+	return m.w - m.editorWidth() - 1
+}
+
+// previewView renders the preview to the height of the editor, keeping its end visible
+// so that the part being typed is shown.
+func (m *Model) previewView(height int) string {
+	preview := strings.Trim(m.state.preview, "\n")
+	if preview == "" {
+		preview = m.theme.MessageInput.CompletionDetail.Render("nothing to preview")
+	}
+
+	lines := strings.Split(preview, "\n")
+	if len(lines) > height {
+		lines = lines[len(lines)-height:]
+	}
+
+	return m.theme.MessageInput.Preview.
+		Width(m.previewWidth()).
+		Height(height).
+		MaxHeight(height).
+		Render(lipgloss.NewStyle().MaxWidth(m.previewWidth() - 1).Render(strings.Join(lines, "\n")))
+}
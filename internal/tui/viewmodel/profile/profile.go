@@ -0,0 +1,500 @@
+package profile
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/blacktop/go-termimg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const (
+	help = "d: direct message · H: home channel · @: mention · h/l: other users · j/k: scroll · esc: back"
+	// iconWidth and iconHeight are the size of the user icon in cells.
+	iconWidth, iconHeight = 16, 8
+	// maxTopStamps bounds the number of stamps listed in the stats.
+	maxTopStamps = 10
+)
+
+type (
+	userFetchedMsg struct {
+		userID uuid.UUID
+		user   *traqapi.UserDetail
+		err    error
+	}
+	statsFetchedMsg struct {
+		userID uuid.UUID
+		stats  *traqapi.UserStats
+	}
+	iconFetchedMsg struct {
+		userID uuid.UUID
+		// icon is nil if the icon is unavailable
+		icon image.Image
+	}
+	dmChannelFetchedMsg struct {
+		channelID uuid.UUID
+		err       error
+	}
+)
+
+type State struct {
+	// userIDs holds the users that can be browsed, e.g. the author and the users
+	// mentioned in a message.
+	userIDs []uuid.UUID
+	current int
+	users   map[uuid.UUID]*traqapi.UserDetail
+	stats   map[uuid.UUID]*traqapi.UserStats
+	// icons holds the rendered icons, which are empty if unavailable.
+	icons map[uuid.UUID]string
+	err   string
+	// notice replaces the status line, e.g. when the DM failed to be opened.
+	notice string
+	// scroll is the number of lines scrolled past.
+	scroll int
+}
+
+type Model struct {
+	w, h        int
+	traqContext *traqapiext.Context
+	theme       shared.Theme
+
+	state State
+}
+
+var _ tea.Model = (*Model)(nil)
+
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
+	return &Model{
+		w:           w,
+		h:           h,
+		traqContext: traqContext,
+		theme:       theme,
+		state: State{
+			users: make(map[uuid.UUID]*traqapi.UserDetail),
+			stats: make(map[uuid.UUID]*traqapi.UserStats),
+			icons: make(map[uuid.UUID]string),
+		},
+	}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+}
+
+func (m *Model) Init() tea.Cmd {
+	return nil
+}
+
+// Open shows the profile of the first user.
+func (m *Model) Open(userIDs []uuid.UUID) tea.Cmd {
+	m.state.userIDs = userIDs
+	m.state.current = 0
+
+	return m.showCurrent()
+}
+
+func (m *Model) currentUserID() uuid.UUID {
+	if len(m.state.userIDs) == 0 {
+		return uuid.Nil
+	}
+
+	return m.state.userIDs[m.state.current]
+}
+
+// showCurrent fetches the profile of the current user. The loaded profile is shown
+// until the caches return, so that browsing does not flicker but stale profiles are
+// replaced.
+func (m *Model) showCurrent() tea.Cmd {
+	m.state.err = ""
+	m.state.notice = ""
+	m.state.scroll = 0
+
+	userID := m.currentUserID()
+	if userID == uuid.Nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	cmds := []tea.Cmd{
+		m.fetchUserCmd(ctx, userID),
+		m.fetchStatsCmd(ctx, userID),
+	}
+	// icons rarely change and are costly to render
+	if _, ok := m.state.icons[userID]; !ok {
+		cmds = append(cmds, m.fetchIconCmd(ctx, userID))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		m.state.notice = ""
+		return m, m.handleKey(msg)
+
+	case userFetchedMsg:
+		if msg.err != nil {
+			if msg.userID == m.currentUserID() {
+				m.state.err = fmt.Sprintf("failed to load the profile: %v", msg.err)
+			}
+
+			return m, nil
+		}
+
+		m.state.users[msg.userID] = msg.user
+
+	case statsFetchedMsg:
+		m.state.stats[msg.userID] = msg.stats
+
+	case iconFetchedMsg:
+		m.state.icons[msg.userID] = renderIcon(msg.icon)
+
+	case dmChannelFetchedMsg:
+		if msg.err != nil {
+			m.state.notice = fmt.Sprintf("failed to open the direct message: %v", msg.err)
+			return m, nil
+		}
+
+		return m, func() tea.Msg {
+			return shared.OpenChannelMsg{
+				Target: &traqapi.Channel{ID: msg.channelID},
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		return func() tea.Msg {
+			return shared.ReturnToChannelContentMsg{}
+		}
+
+	case "l", "right", "]":
+		if m.state.current < len(m.state.userIDs)-1 {
+			m.state.current++
+			return m.showCurrent()
+		}
+
+	case "h", "left", "[":
+		if m.state.current > 0 {
+			m.state.current--
+			return m.showCurrent()
+		}
+
+	case "j", "down":
+		m.state.scroll = min(m.state.scroll+1, max(len(m.bodyLines())-(m.h-1), 0))
+
+	case "k", "up":
+		m.state.scroll = max(m.state.scroll-1, 0)
+
+	case "d":
+		userID := m.currentUserID()
+		if userID == uuid.Nil {
+			return nil
+		}
+
+		return m.fetchDMChannelCmd(context.Background(), userID)
+
+	case "H":
+		user, ok := m.state.users[m.currentUserID()]
+		if !ok {
+			return nil
+		}
+
+		homeID, ok := user.GetHomeChannel().Get()
+		if !ok {
+			m.state.notice = "no home channel"
+			return nil
+		}
+
+		channel, ok := m.traqContext.ChannelByID(homeID)
+		if !ok {
+			channel = traqapi.Channel{ID: homeID}
+		}
+
+		return func() tea.Msg {
+			return shared.OpenChannelMsg{Target: &channel}
+		}
+
+	case "@":
+		user, ok := m.state.users[m.currentUserID()]
+		if !ok {
+			return nil
+		}
+
+		return func() tea.Msg {
+			return shared.MentionUserMsg{
+				UserID: user.GetID(),
+				Name:   user.GetName(),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Model) View() string {
+	lines := m.bodyLines()
+	scroll := min(m.state.scroll, max(len(lines)-(m.h-1), 0))
+	lines = lines[scroll:]
+
+	body := lipgloss.NewStyle().
+		Height(m.h - 1).
+		MaxHeight(m.h - 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.NewStyle().
+		Width(m.w).
+		Height(m.h).
+		Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			body,
+			m.theme.Profile.Meta.Render(ansi.Truncate(help, m.w, "…")),
+		))
+}
+
+// bodyLines renders the icon next to the summary, followed by the details.
+func (m *Model) bodyLines() []string {
+	userID := m.currentUserID()
+	user, ok := m.state.users[userID]
+
+	status := ""
+	switch {
+	case m.state.notice != "":
+		status = m.theme.Profile.Error.Render(m.state.notice)
+	case m.state.err != "":
+		status = m.theme.Profile.Error.Render(m.state.err)
+	case !ok:
+		status = m.theme.Profile.Meta.Render("loading…")
+	}
+
+	if !ok {
+		return strings.Split(status, "\n")
+	}
+
+	summary := m.summaryView(user)
+	if icon := m.state.icons[userID]; icon != "" {
+		summary = lipgloss.JoinHorizontal(lipgloss.Top, icon, " ", summary)
+	}
+
+	parts := []string{summary}
+	if status != "" {
+		parts = append(parts, status)
+	}
+
+	if bio := strings.TrimSpace(user.GetBio()); bio != "" {
+		parts = append(parts, m.section("Bio", bio))
+	}
+
+	parts = append(parts,
+		m.section(fmt.Sprintf("Groups (%d)", len(user.GetGroups())), m.groupsView(user.GetGroups())),
+		m.section(fmt.Sprintf("Tags (%d)", len(user.GetTags())), m.tagsView(user.GetTags())),
+	)
+
+	if stats, ok := m.state.stats[userID]; ok && stats != nil {
+		parts = append(parts, m.section("Stats", m.statsView(stats)))
+	}
+
+	rendered := lipgloss.NewStyle().Width(m.w).Render(strings.Join(parts, "\n\n"))
+	return strings.Split(rendered, "\n")
+}
+
+func (m *Model) summaryView(user *traqapi.UserDetail) string {
+	name := "@" + user.GetName()
+	if user.GetBot() {
+		name += " [bot]"
+	}
+
+	lines := []string{
+		m.theme.Profile.DisplayName.Render(cmp.Or(user.GetDisplayName(), user.GetName())),
+		name,
+	}
+
+	switch user.GetState() {
+	case traqapi.UserAccountState0:
+		lines = append(lines, m.theme.Profile.Meta.Render("deactivated"))
+	case traqapi.UserAccountState2:
+		lines = append(lines, m.theme.Profile.Meta.Render("suspended"))
+	}
+
+	if m.traqContext.IsOnline(user.GetID()) {
+		lines = append(lines, m.theme.PresenceDot(true)+" online")
+	} else if lastOnline, ok := user.GetLastOnline().Get(); ok {
+		lines = append(lines, m.theme.PresenceDot(false)+m.theme.Profile.Meta.Render(" last online "+lastOnline.Local().Format("2006/01/02 15:04")))
+	}
+
+	if homeID, ok := user.GetHomeChannel().Get(); ok {
+		home := "(private)"
+		if path, ok := m.traqContext.ChannelPath(homeID); ok {
+			home = "#" + path
+		}
+
+		lines = append(lines, m.theme.Profile.Meta.Render("home "+home))
+	}
+
+	if len(m.state.userIDs) > 1 {
+		lines = append(lines, m.theme.Profile.Meta.Render(fmt.Sprintf("user %d/%d", m.state.current+1, len(m.state.userIDs))))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderIcon renders the icon as half blocks, or returns "" if it is unavailable.
+func renderIcon(icon image.Image) string {
+	if icon == nil {
+		return ""
+	}
+
+	rendered, err := termimg.New(icon).
+		Protocol(termimg.Halfblocks).
+		Size(iconWidth, iconHeight).
+		Render()
+	if err != nil {
+		return ""
+	}
+
+	return rendered
+}
+
+func (m *Model) section(heading, content string) string {
+	return m.theme.Profile.Section.Render(heading) + "\n" + content
+}
+
+func (m *Model) groupsView(groupIDs []uuid.UUID) string {
+	if len(groupIDs) == 0 {
+		return m.theme.Profile.Meta.Render("no groups")
+	}
+
+	groups, _ := m.traqContext.UserGroups.GetIfExists(struct{}{})
+	names := make([]string, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		i := slices.IndexFunc(groups, func(group traqapi.UserGroup) bool {
+			return group.GetID() == groupID
+		})
+		if i < 0 {
+			continue
+		}
+
+		names = append(names, "@"+groups[i].GetName())
+	}
+	slices.Sort(names)
+
+	if len(names) < len(groupIDs) {
+		names = append(names, m.theme.Profile.Meta.Render(fmt.Sprintf("and %d more", len(groupIDs)-len(names))))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (m *Model) tagsView(tags []traqapi.UserTag) string {
+	if len(tags) == 0 {
+		return m.theme.Profile.Meta.Render("no tags")
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.GetTag())
+	}
+
+	return strings.Join(names, " · ")
+}
+
+func (m *Model) statsView(stats *traqapi.UserStats) string {
+	stamps := slices.Clone(stats.GetStamps())
+	slices.SortFunc(stamps, func(a, b traqapi.UserStatsStamp) int {
+		return cmp.Compare(b.GetTotal(), a.GetTotal())
+	})
+
+	var totalStamps int64
+	for _, stamp := range stamps {
+		totalStamps += stamp.GetTotal()
+	}
+
+	summary := fmt.Sprintf("%d messages · %d stamps", stats.GetTotalMessageCount(), totalStamps)
+
+	top := make([]string, 0, maxTopStamps)
+	for _, stamp := range stamps[:min(len(stamps), maxTopStamps)] {
+		name := "unknown"
+		if s, ok := m.traqContext.StampByID(stamp.GetID()); ok {
+			name = s.GetName()
+		}
+
+		top = append(top, fmt.Sprintf(":%s: ×%d", name, stamp.GetTotal()))
+	}
+
+	if len(top) == 0 {
+		return summary
+	}
+
+	return summary + "\n" + m.theme.Profile.Meta.Render(strings.Join(top, "  "))
+}
+
+func (m *Model) fetchUserCmd(ctx context.Context, userID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := m.traqContext.UserGroups.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "load user groups for profile", "error", err)
+		}
+
+		user, err := m.traqContext.UserDetails.Get(ctx, userID)
+		return userFetchedMsg{
+			userID: userID,
+			user:   user,
+			err:    err,
+		}
+	}
+}
+
+func (m *Model) fetchStatsCmd(ctx context.Context, userID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		stats, err := m.traqContext.UserStats.Get(ctx, userID)
+		if err != nil {
+			slog.WarnContext(ctx, "load user stats", "userID", userID, "error", err)
+			return nil
+		}
+
+		return statsFetchedMsg{
+			userID: userID,
+			stats:  stats,
+		}
+	}
+}
+
+func (m *Model) fetchIconCmd(ctx context.Context, userID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		icon, err := m.traqContext.UserIcons.Get(ctx, userID)
+		if err != nil {
+			slog.WarnContext(ctx, "load user icon", "userID", userID, "error", err)
+			return iconFetchedMsg{userID: userID}
+		}
+
+		return iconFetchedMsg{
+			userID: userID,
+			icon:   icon,
+		}
+	}
+}
+
+func (m *Model) fetchDMChannelCmd(ctx context.Context, userID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		channelID, err := m.traqContext.DMChannelID(ctx, userID)
+		return dmChannelFetchedMsg{
+			channelID: channelID,
+			err:       err,
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/ras0q/lazytraq/internal/traqapi"
+	"github.com/ras0q/lazytraq/internal/traqapiext"
+	"github.com/ras0q/lazytraq/internal/tui/shared"
+)
+
+const (
+	inputHelp   = "enter: search · esc: close · from:@user to:@user in:#channel has:image|video|audio|url|attachments before:/after:2006-01-02 is:bot"
+	resultsHelp = "j/k: select · enter: jump · h/l: page · /: edit query · esc: back"
+)
+
+type (
+	searchResultMsg struct {
+		seq   int
+		page  int
+		hits  []traqapi.Message
+		total int64
+		err   error
+	}
+)
+
+type State struct {
+	params traqapi.SearchMessagesParams
+	page   int
+	hits   []traqapi.Message
+	total  int64
+	// selected is the index of the selected hit in hits.
+	selected int
+	// seq identifies the latest search, so that stale results are dropped.
+	seq     int
+	loading bool
+	err     string
+	// searched is true once a query has been run.
+	searched bool
+}
+
+type Model struct {
+	w, h        int
+	traqContext *traqapiext.Context
+	theme       shared.Theme
+	input       textinput.Model
+
+	state State
+}
+
+var _ tea.Model = (*Model)(nil)
+
+func New(w, h int, traqContext *traqapiext.Context, theme shared.Theme) *Model {
+	input := textinput.New()
+	input.Prompt = "search: "
+	input.Placeholder = "from:@alice in:#general has:image before:2026-01-01 words"
+
+	return &Model{
+		w:           w,
+		h:           h,
+		traqContext: traqContext,
+		theme:       theme,
+		input:       input,
+	}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.w, m.h = w, h
+	m.input.Width = w - lipgloss.Width(m.input.Prompt) - 1
+}
+
+func (m *Model) Init() tea.Cmd {
+	return m.prefetchCmd(context.Background())
+}
+
+// Open focuses the query input.
+func (m *Model) Open() tea.Cmd {
+	return m.input.Focus()
+}
+
+// Typing reports whether keys are being typed into the query.
+func (m *Model) Typing() bool {
+	return m.input.Focused()
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			return m, m.handleInputKey(msg)
+		}
+
+		return m, m.handleResultsKey(msg)
+
+	case searchResultMsg:
+		if msg.seq != m.state.seq {
+			return m, nil
+		}
+
+		m.state.loading = false
+		if msg.err != nil {
+			m.state.err = searchFailedMessage(msg.err)
+			return m, nil
+		}
+
+		m.state.page = msg.page
+		m.state.hits = msg.hits
+		m.state.total = msg.total
+		m.state.selected = 0
+		return m, nil
+	}
+
+	// e.g. cursor blinks
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+
+	return m, cmd
+}
+
+func (m *Model) handleInputKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.input.Blur()
+		return func() tea.Msg {
+			return shared.ReturnToSidebarMsg{}
+		}
+
+	case "enter":
+		params, err := m.traqContext.ParseSearchQuery(m.input.Value())
+		if err != nil {
+			m.state.err = err.Error()
+			return nil
+		}
+
+		m.state.params = params
+		m.state.searched = true
+		m.input.Blur()
+
+		return m.searchCmd(context.Background(), 0)
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+
+	return cmd
+}
+
+func (m *Model) handleResultsKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "/", "i":
+		return m.input.Focus()
+
+	case "j", "down":
+		m.state.selected = min(m.state.selected+1, max(len(m.state.hits)-1, 0))
+
+	case "k", "up":
+		m.state.selected = max(m.state.selected-1, 0)
+
+	case "l", "right", "]":
+		if int64((m.state.page+1)*traqapiext.SearchPageSize) < m.state.total {
+			return m.searchCmd(context.Background(), m.state.page+1)
+		}
+
+	case "h", "left", "[":
+		if m.state.page > 0 {
+			return m.searchCmd(context.Background(), m.state.page-1)
+		}
+
+	case "enter":
+		if len(m.state.hits) == 0 {
+			return nil
+		}
+
+		hit := m.state.hits[m.state.selected]
+		return func() tea.Msg {
+			return shared.JumpToMessageMsg{
+				ChannelID: hit.GetChannelId(),
+				MessageID: hit.GetID(),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Model) View() string {
+	lines := []string{m.input.View(), m.statusView()}
+
+	// each hit takes a line for its channel, author and time and one for its content
+	visible := max((m.h-3)/2, 1)
+	start := max(m.state.selected-visible+1, 0)
+	for i := start; i < min(start+visible, len(m.state.hits)); i++ {
+		lines = append(lines, m.hitView(m.state.hits[i], i == m.state.selected)...)
+	}
+
+	help := resultsHelp
+	if m.input.Focused() {
+		help = inputHelp
+	}
+
+	body := lipgloss.NewStyle().
+		Height(m.h - 1).
+		MaxHeight(m.h - 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.NewStyle().
+		Width(m.w).
+		Height(m.h).
+		Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			body,
+			m.theme.Search.Meta.Render(ansi.Truncate(help, m.w, "…")),
+		))
+}
+
+func (m *Model) statusView() string {
+	switch {
+	case m.state.err != "":
+		return m.theme.Search.Error.Render(m.state.err)
+
+	case m.state.loading:
+		return m.theme.Search.Meta.Render("searching…")
+
+	case !m.state.searched:
+		return ""
+
+	case m.state.total == 0:
+		return m.theme.Search.Meta.Render("no messages found")
+
+	default:
+		pages := (m.state.total + traqapiext.SearchPageSize - 1) / traqapiext.SearchPageSize
+		return m.theme.Search.Meta.Render(
+			fmt.Sprintf("%d hits · page %d/%d", m.state.total, m.state.page+1, pages),
+		)
+	}
+}
+
+func (m *Model) hitView(hit traqapi.Message, selected bool) []string {
+	channel := "#" + cmp.Or(m.channelPath(hit.GetChannelId()), "(private)")
+	username := "unknown"
+	if user, ok := m.traqContext.UserByID(hit.GetUserId()); ok {
+		username = user.GetName()
+	}
+
+	meta := fmt.Sprintf(
+		"%s · @%s · %s",
+		channel,
+		username,
+		hit.GetCreatedAt().Local().Format("2006/01/02 15:04"),
+	)
+
+	md := m.traqContext.ParseMarkdown(hit.GetContent(), traqapiext.MarkdownOptions{})
+	content := strings.Join(strings.Fields(md.Content), " ")
+
+	marker, style := "  ", m.theme.Search.Hit
+	if selected {
+		marker, style = "> ", m.theme.Search.SelectedHit
+	}
+
+	return []string{
+		ansi.Truncate(marker+m.theme.Search.Meta.Render(meta), m.w, "…"),
+		ansi.Truncate("  "+style.Render(content), m.w, "…"),
+	}
+}
+
+func (m *Model) channelPath(channelID uuid.UUID) string {
+	path, _ := m.traqContext.ChannelPath(channelID)
+	return path
+}
+
+func (m *Model) searchCmd(ctx context.Context, page int) tea.Cmd {
+	m.state.seq++
+	m.state.loading = true
+	m.state.err = ""
+	seq := m.state.seq
+	params := m.state.params
+
+	return func() tea.Msg {
+		hits, total, err := m.traqContext.SearchMessages(ctx, params, page)
+		return searchResultMsg{
+			seq:   seq,
+			page:  page,
+			hits:  hits,
+			total: total,
+			err:   err,
+		}
+	}
+}
+
+// prefetchCmd loads the users and channels the query refers to by name.
+func (m *Model) prefetchCmd(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := m.traqContext.Users.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "prefetch users for search", "error", err)
+		}
+
+		if _, err := m.traqContext.Channels.Get(ctx, struct{}{}); err != nil {
+			slog.WarnContext(ctx, "prefetch channels for search", "error", err)
+		}
+
+		return nil
+	}
+}
+
+func searchFailedMessage(err error) string {
+	if errors.Is(err, traqapiext.ErrOffline) {
+		return "offline: search is unavailable"
+	}
+
+	return fmt.Sprintf("search failed: %v", err)
+}
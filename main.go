@@ -8,10 +8,12 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"sync"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/ras0q/goalie"
 	"github.com/ras0q/lazytraq/internal/auth"
+	"github.com/ras0q/lazytraq/internal/notify"
 	"github.com/ras0q/lazytraq/internal/traqapiext"
 	"github.com/ras0q/lazytraq/internal/tui"
 	"golang.org/x/term"
@@ -19,6 +21,15 @@ import (
 
 func main() {
 	ctx := context.Background()
+
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		if err := runCacheCommand(os.Args[2:]); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
 	if err := runProgram(ctx); err != nil {
 		panic(err)
 	}
@@ -77,10 +88,18 @@ func runProgram(ctx context.Context) (err error) {
 
 	slog.DebugContext(ctx, "got terminal size", "width", w, "height", h)
 
-	model, err := tui.NewAppModel(w, h, apiHost, securitySource)
+	// LAZYTRAQ_NOTIFY lists where to notify of mentions, e.g. "bell,osc9"
+	terminal := notify.NewTerminal()
+	sinks, err := notify.ParseSinks(os.Getenv("LAZYTRAQ_NOTIFY"), terminal)
+	if err != nil {
+		return fmt.Errorf("parse LAZYTRAQ_NOTIFY: %w", err)
+	}
+
+	model, err := tui.NewAppModel(w, h, apiHost, securitySource, sinks, terminal)
 	if err != nil {
 		return fmt.Errorf("create root model: %w", err)
 	}
+	defer g.Guard(model.Close)
 
 	slog.DebugContext(ctx, "created root model")
 
@@ -110,25 +129,61 @@ func loginToTraq(ctx context.Context, apiHost string) (*traqapiext.SecuritySourc
 		}
 	}()
 
-	token, tokenStore, err := auth.GetToken(ctx, apiHost, authURLCh)
+	fileKey := sync.OnceValues(loadFileKey)
+
+	token, tokenStore, err := auth.GetToken(ctx, apiHost, fileKey, authURLCh)
 	if err != nil {
 		return nil, fmt.Errorf("get token: %w", err)
 	}
 
-	if tokenStore == auth.TokenStoreFile {
-		slog.WarnContext(ctx, "got token from file, consider using keyring")
-	}
+	switch tokenStore {
+	case auth.TokenStoreFile:
+		slog.WarnContext(ctx, "got plaintext token from file, migrating")
 
-	if tokenStore == auth.TokenStoreWeb {
-		tokenStore, err = auth.SetToken(apiHost, token)
+		tokenStore, err = auth.MigrateFileToken(apiHost, token, fileKey)
 		if err != nil {
-			return nil, fmt.Errorf("set token: %w", err)
+			// the plaintext token still works, so the migration is retried next time
+			slog.WarnContext(ctx, "migrate token, keeping the plaintext token file", "error", err)
+			tokenStore = auth.TokenStoreFile
 		}
 
-		if tokenStore == auth.TokenStoreFile {
-			slog.WarnContext(ctx, "saved token to file, consider using keyring")
+	case auth.TokenStoreWeb:
+		tokenStore, err = auth.SetToken(apiHost, token, fileKey)
+		if err != nil {
+			return nil, fmt.Errorf("set token: %w", err)
 		}
 	}
 
+	if tokenStore == auth.TokenStoreEncryptedFile {
+		slog.WarnContext(ctx, "using encrypted token file, consider using keyring")
+	}
+
 	return traqapiext.NewSecuritySource(token.AccessToken), nil
 }
+
+// loadFileKey returns the key for the encrypted token file.
+// LAZYTRAQ_AGE_IDENTITY (a path to an age identity file) takes precedence over
+// LAZYTRAQ_PASSPHRASE; if neither is set, the passphrase is read from the terminal.
+func loadFileKey() (*auth.FileKey, error) {
+	if identityPath, ok := os.LookupEnv("LAZYTRAQ_AGE_IDENTITY"); ok && identityPath != "" {
+		return auth.LoadIdentityFileKey(identityPath)
+	}
+
+	if passphrase, ok := os.LookupEnv("LAZYTRAQ_PASSPHRASE"); ok && passphrase != "" {
+		return auth.NewPassphraseFileKey(passphrase)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("keyring is unavailable; set LAZYTRAQ_AGE_IDENTITY or LAZYTRAQ_PASSPHRASE")
+	}
+
+	fmt.Print("Passphrase for the lazytraq token file: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+
+	return auth.NewPassphraseFileKey(string(passphrase))
+}